@@ -0,0 +1,264 @@
+package govalidators
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//required 类验证器，值为 nil 指针时也会执行
+var requiredValidators = map[string]bool{
+	"required":         true,
+	"required_if":      true,
+	"required_unless":  true,
+	"required_with":    true,
+	"required_without": true,
+}
+
+//由验证流程处理的 tag，不对应验证器
+var flowRules = map[string]bool{
+	VALIDATOR_OMIT_EMPTY: true,
+	VALIDATOR_OMIT_NIL:   true,
+	VALIDATOR_DIVE:       true,
+	VALIDATOR_KEYS:       true,
+	VALIDATOR_END_KEYS:   true,
+}
+
+var (
+	validatorT   = reflect.TypeOf((*Validator)(nil)).Elem()
+	validatorFT  = reflect.TypeOf((*ValidatorF)(nil)).Elem()
+	validatableT = reflect.TypeOf((*Validatable)(nil)).Elem()
+	ctxT         = reflect.TypeOf((*ValidatorCtx)(nil)).Elem()
+	ctxFT        = reflect.TypeOf((*ValidatorCtxF)(nil)).Elem()
+)
+
+//struct 类型解析后的验证计划，按 reflect.Type 缓存，避免每次验证都重复解析 tag
+type structPlan struct {
+	fields           []*fieldPlan
+	structValidators []StructValidatorF //RegisterStructValidator 注册的验证器
+}
+
+//根据 struct 字段名获取字段的验证计划
+func (self *structPlan) getField(name string) *fieldPlan {
+	for _, field := range self.fields {
+		if field.field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+//struct 字段的验证计划
+type fieldPlan struct {
+	index    int
+	field    reflect.StructField
+	name     string //错误提示中的字段名，设置了 title 时为 title 的值
+	pathName string //字段路径中的字段名
+	tag      string
+	rules    []*rulePlan
+	required bool //是否配置了 required 类验证器
+	embedded bool //嵌入的 struct，字段提升到上级路径中
+	recurse  bool //是否递归验证字段的值，未导出的字段默认不递归验证
+}
+
+//tag 中单个验证器的解析结果
+type rulePlan struct {
+	groups    []string //所属分组，为空时总是执行
+	key       string
+	args      []string
+	required  bool //是否为 required 类验证器，值为 nil 指针时也会执行
+	validator Validator
+	copy      bool        //验证器为 struct 指针，验证时需要做对象拷贝
+	err       error       //验证器不存在或类型错误
+	op        string      //组合验证器的运算符，|、!、&，为空时是单个验证器
+	items     []*rulePlan //组合的验证器
+}
+
+//验证器的字符串形式，如 string=1,5、email|mobile
+func (self *rulePlan) String() string {
+	if self.op != "" || self.args == nil {
+		return self.key
+	}
+	args := make([]string, 0, len(self.args))
+	for _, arg := range self.args {
+		args = append(args, quoteArg(arg))
+	}
+	return self.key + VALIDATOR_VALUE_SIGN + strings.Join(args, VALIDATOR_RANGE_SPLIT)
+}
+
+//获取验证器，struct 指针类型的验证器在并发条件下会导致结构体值被覆盖，需要做对象拷贝，同一次验证中复用拷贝后的对象
+func (self *rulePlan) getValidator(params *itemParams) Validator {
+	if !self.copy {
+		return self.validator
+	}
+	if cacheValidator, ok := params.structValidator[self.key]; ok {
+		return cacheValidator
+	}
+	var validator Validator
+	if ctxV, ok := self.validator.(ctxValidator); ok {
+		validator = ctxValidator{validator: copyValidator(ctxV.validator).(ValidatorCtx)}
+	} else {
+		validator = copyValidator(self.validator).(Validator)
+	}
+	params.structValidator[self.key] = validator
+	return validator
+}
+
+//拷贝 struct 指针类型的验证器
+func copyValidator(validator interface{}) interface{} {
+	vV := reflect.ValueOf(validator)
+	baseValidator := reflect.New(vV.Elem().Type())
+	baseValidator.Elem().Set(vV.Elem())
+	return baseValidator.Interface()
+}
+
+//获取 struct 类型的验证计划，没有缓存时解析并缓存
+func (self *goValidator) getStructPlan(typeObj reflect.Type) *structPlan {
+	if plan, ok := self.planCache.Load(typeObj); ok {
+		return plan.(*structPlan)
+	}
+	//解析和写入缓存都在读锁内完成，避免修改配置时写入旧配置解析的结果
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+	plan, _ := self.planCache.LoadOrStore(typeObj, self.compileStructPlan(typeObj))
+	return plan.(*structPlan)
+}
+
+//配置修改后，清空验证计划缓存，调用方需持有写锁
+func (self *goValidator) resetPlanCache() {
+	self.planCache.Range(func(key, value interface{}) bool {
+		self.planCache.Delete(key)
+		return true
+	})
+}
+
+func (self *goValidator) compileStructPlan(typeObj reflect.Type) *structPlan {
+	numField := typeObj.NumField()
+	plan := &structPlan{
+		fields:           make([]*fieldPlan, 0, numField),
+		structValidators: self.structValidators[typeObj],
+	}
+	for i := 0; i < numField; i++ {
+		fieldTypeInfo := typeObj.Field(i)
+		field := &fieldPlan{
+			index:    i,
+			field:    fieldTypeInfo,
+			name:     fieldTypeInfo.Name,
+			pathName: self.fieldNameFunc(fieldTypeInfo),
+			tag:      fieldTypeInfo.Tag.Get(self.tagName),
+		}
+		if title := fieldTypeInfo.Tag.Get(self.TitleTag); title != "" {
+			field.name = title
+		}
+		if field.tag != "" {
+			field.rules = self.compileRules(field.tag)
+		}
+		field.required = hasRequiredRule(field.rules)
+		//和 encoding/json 一致，json tag 中设置了字段名(如 json:"base")的嵌入 struct 不提升，和 SetFieldNameFunc 无关
+		embeddedType := fieldTypeInfo.Type
+		if embeddedType.Kind() == reflect.Ptr {
+			embeddedType = embeddedType.Elem()
+		}
+		jsonName := strings.Split(fieldTypeInfo.Tag.Get("json"), ",")[0]
+		field.embedded = self.flattenEmbedded && fieldTypeInfo.Anonymous && embeddedType.Kind() == reflect.Struct && (jsonName == "" || jsonName == "-")
+		field.recurse = fieldTypeInfo.PkgPath == "" || field.embedded || self.validateUnexported
+		plan.fields = append(plan.fields, field)
+	}
+	return plan
+}
+
+//判断是否配置了 required 类验证器，dive 后边的验证器作用于元素，不影响值本身是否必填
+func hasRequiredRule(rules []*rulePlan) bool {
+	for _, rule := range rules {
+		if rule.key == VALIDATOR_DIVE {
+			break
+		}
+		if rule.required {
+			return true
+		}
+	}
+	return false
+}
+
+//ValidateVar 中验证规则的缓存 key，和 struct 类型的缓存区分开
+type varPlanKey string
+
+//获取 ValidateVar 中验证规则的解析结果，没有缓存时解析并缓存
+func (self *goValidator) getVarRules(tag string) []*rulePlan {
+	if rules, ok := self.planCache.Load(varPlanKey(tag)); ok {
+		return rules.([]*rulePlan)
+	}
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+	rules, _ := self.planCache.LoadOrStore(varPlanKey(tag), self.compileRules(tag))
+	return rules.([]*rulePlan)
+}
+
+//解析 tag 中的验证器，如 required||string=1,5、email|mobile、!in=admin,root
+func (self *goValidator) compileRules(tag string) (rules []*rulePlan) {
+	parser := &ruleParser{validator: self, tag: tag}
+	rules = parser.parse()
+	checkKeysRules(rules)
+	//dive、keys、endkeys 决定后边的验证器作用于哪个值，不能只在部分分组中生效
+	for _, rule := range rules {
+		if rule.err == nil && len(rule.groups) > 0 && flowRules[rule.key] && rule.key != VALIDATOR_OMIT_EMPTY && rule.key != VALIDATOR_OMIT_NIL {
+			rule.err = fmt.Errorf("validator %v can not have groups", rule.key)
+		}
+	}
+	return
+}
+
+//检查 keys、endkeys 的位置，keys 必须紧跟在 dive 后边，并且有对应的 endkeys
+func checkKeysRules(rules []*rulePlan) {
+	inKeys := false
+	for i, rule := range rules {
+		switch rule.key {
+		case VALIDATOR_KEYS:
+			if i == 0 || rules[i-1].key != VALIDATOR_DIVE {
+				rule.err = fmt.Errorf("validator %v must follow %v", VALIDATOR_KEYS, VALIDATOR_DIVE)
+				continue
+			}
+			rule.err = fmt.Errorf("validator %v must end with %v", VALIDATOR_KEYS, VALIDATOR_END_KEYS)
+			for _, endRule := range rules[i+1:] {
+				if endRule.key == VALIDATOR_END_KEYS {
+					rule.err = nil
+					break
+				}
+			}
+			inKeys = rule.err == nil
+		case VALIDATOR_END_KEYS:
+			if !inKeys {
+				rule.err = fmt.Errorf("validator %v must follow %v", VALIDATOR_END_KEYS, VALIDATOR_KEYS)
+			}
+			inKeys = false
+		}
+	}
+}
+
+//根据 key 获取注册的验证器，验证器必须满足 ValidatorF、ValidatorCtxF 类型或实现 Validator、ValidatorCtx 接口
+func (self *goValidator) resolveValidator(vK string) (validator Validator, copy bool, err error) {
+	tmpValidator, ok := self.validator[vK]
+	if !ok {
+		err = fmt.Errorf("validator %v not exist", vK)
+		return
+	}
+	vT := reflect.TypeOf(tmpValidator)
+	if vT == nil {
+		err = fmt.Errorf("validator %v error", vK)
+		return
+	}
+	if vT.Implements(validatorT) {
+		validator = tmpValidator.(Validator)
+		copy = vT.Kind() == reflect.Ptr && vT.Elem().Kind() == reflect.Struct
+	} else if vT.ConvertibleTo(validatorFT) {
+		validator = reflect.ValueOf(tmpValidator).Convert(validatorFT).Interface().(ValidatorF)
+	} else if vT.Implements(ctxT) {
+		validator = ctxValidator{validator: tmpValidator.(ValidatorCtx)}
+		copy = vT.Kind() == reflect.Ptr && vT.Elem().Kind() == reflect.Struct
+	} else if vT.ConvertibleTo(ctxFT) {
+		validator = ctxValidator{validator: reflect.ValueOf(tmpValidator).Convert(ctxFT).Interface().(ValidatorCtxF)}
+	} else {
+		err = fmt.Errorf("validator %v error", vK)
+	}
+	return
+}
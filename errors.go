@@ -0,0 +1,87 @@
+package govalidators
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+//验证器配置错误，如参数错误、字段不存在、类型不支持，可以通过 errors.Is(err, ErrValidatorConfig) 判断
+//非运算(!)只对验证不通过取反，配置错误直接返回；自定义验证器可以通过 fmt.Errorf("...: %w", ErrValidatorConfig) 返回配置错误
+var ErrValidatorConfig = errors.New("validator config error")
+
+//验证错误，Validate/LazyValidate 及内置验证器返回的错误类型
+type ValidationError struct {
+	Field string      //字段名，设置了 title 时为 title 的值
+	Path  string      //字段路径
+	Rule  string      //验证器 key，如 string、integer
+	Args  []string    //验证器参数，如 string=1,5 中的 [1 5]
+	Value interface{} //字段值
+	Msg   string      //错误提示
+	err   error       //被包装的普通 error，如 context.Canceled
+}
+
+func (self *ValidationError) Error() string {
+	return self.Msg
+}
+
+//返回被包装的 error，可以通过 errors.Is 判断，如 errors.Is(err, context.Canceled)
+func (self *ValidationError) Unwrap() error {
+	return self.err
+}
+
+//将验证器返回的 error 补全为 *ValidationError，自定义验证器返回普通 error 时，会包装成 *ValidationError
+func toValidationError(err error, field, path, rule string, args []string, val reflect.Value) *ValidationError {
+	vErr, ok := err.(*ValidationError)
+	if !ok || vErr == nil {
+		vErr = &ValidationError{err: err}
+		if err != nil {
+			vErr.Msg = err.Error()
+		} else {
+			vErr.Msg = field + " is invalid"
+		}
+	}
+	if vErr.Field == "" {
+		vErr.Field = field
+	}
+	if vErr.Path == "" {
+		vErr.Path = path
+	}
+	if vErr.Rule == "" {
+		vErr.Rule = rule
+	}
+	if vErr.Args == nil {
+		vErr.Args = args
+	}
+	if vErr.Value == nil {
+		vErr.Value = valueInterface(val)
+	}
+	return vErr
+}
+
+//字段名生成函数，用于生成错误中的字段路径
+type FieldNameFunc func(field reflect.StructField) string
+
+//使用 struct 字段名作为路径中的字段名
+func StructFieldName(field reflect.StructField) string {
+	return field.Name
+}
+
+//使用 json tag 中的名称作为路径中的字段名，未设置或为 - 时使用 struct 字段名
+func JsonFieldName(field reflect.StructField) string {
+	return TagFieldName("json")(field)
+}
+
+//使用指定 tag 的值作为路径中的字段名，如 TagFieldName("title")，未设置或为 - 时使用 struct 字段名
+func TagFieldName(tag string) FieldNameFunc {
+	return func(field reflect.StructField) string {
+		name := field.Tag.Get(tag)
+		if num := strings.Index(name, ","); num != -1 {
+			name = name[:num]
+		}
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	}
+}
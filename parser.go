@@ -0,0 +1,286 @@
+package govalidators
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	//或，如 email|mobile
+	VALIDATOR_OR = "|"
+	//非，如 !in=admin,root
+	VALIDATOR_NOT = "!"
+	//括号分组，如 (email||string=_,50)|mobile
+	VALIDATOR_GROUP_BEGIN = "("
+	VALIDATOR_GROUP_END   = ")"
+	//参数可以使用的引号，如 in='a,b','c'
+	VALIDATOR_QUOTES = `'"`
+	//括号中用 || 分隔的验证器，组合后的运算符
+	VALIDATOR_AND = "&"
+)
+
+/**
+ * tag 解析器，按以下语法解析 tag，验证器分隔符(默认为 ||)表示且，优先级最低
+ * and  = or { 分隔符 or }
+ * or   = not { "|" not }
+ * not  = "!" not | "(" and ")" | rule
+ * rule = key [ "=" arg { "," arg } ]
+ * arg  = 带引号的参数 | 不带引号的参数
+ * 最外层的每一项可以带分组前缀，如 create,update:email|mobile
+ */
+type ruleParser struct {
+	validator *goValidator
+	tag       string
+	pos       int
+	depth     int //当前所在括号的层数
+}
+
+//解析 tag，返回最外层用分隔符分隔的验证器，解析出错时，最后一项的 err 为解析错误
+func (self *ruleParser) parse() (rules []*rulePlan) {
+	for {
+		groups := self.parseGroups()
+		rule, err := self.parseOr()
+		if err == nil && self.pos < len(self.tag) && !self.peek(self.validator.validatorSplit) {
+			err = self.errorf("unexpected %q", self.tag[self.pos:self.pos+1])
+		}
+		if err != nil {
+			return append(rules, &rulePlan{groups: groups, key: self.tag, err: err})
+		}
+		rule.groups = groups
+		rules = append(rules, rule)
+		if !self.consume(self.validator.validatorSplit) {
+			return
+		}
+	}
+}
+
+//解析分组前缀，分组在第一个赋值符号、运算符之前，如 create,update:string=1,5
+func (self *ruleParser) parseGroups() (groups []string) {
+	for i := self.pos; i < len(self.tag); i++ {
+		if self.isDelimiter(i) || strings.ContainsAny(self.tag[i:i+1], VALIDATOR_VALUE_SIGN+VALIDATOR_NOT+VALIDATOR_GROUP_BEGIN) {
+			return
+		}
+		if strings.HasPrefix(self.tag[i:], VALIDATOR_GROUP_SIGN) {
+			groups = strings.Split(self.tag[self.pos:i], VALIDATOR_GROUP_SPLIT)
+			self.pos = i + len(VALIDATOR_GROUP_SIGN)
+			return
+		}
+	}
+	return
+}
+
+//解析括号中用分隔符分隔的验证器，只有一项时直接返回该项
+func (self *ruleParser) parseAnd() (*rulePlan, error) {
+	var items []*rulePlan
+	for {
+		rule, err := self.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, rule)
+		if !self.consume(self.validator.validatorSplit) {
+			break
+		}
+	}
+	return self.combineRules(VALIDATOR_AND, items), nil
+}
+
+func (self *ruleParser) parseOr() (*rulePlan, error) {
+	var items []*rulePlan
+	for {
+		rule, err := self.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, rule)
+		//分隔符也以 | 开头时，优先作为分隔符
+		if self.peek(self.validator.validatorSplit) || !self.consume(VALIDATOR_OR) {
+			break
+		}
+	}
+	return self.combineRules(VALIDATOR_OR, items), nil
+}
+
+func (self *ruleParser) parseNot() (*rulePlan, error) {
+	if self.consume(VALIDATOR_NOT) {
+		rule, err := self.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return self.combineRules(VALIDATOR_NOT, []*rulePlan{rule}), nil
+	}
+	if self.consume(VALIDATOR_GROUP_BEGIN) {
+		self.depth++
+		rule, err := self.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if !self.consume(VALIDATOR_GROUP_END) {
+			return nil, self.errorf("missing %q", VALIDATOR_GROUP_END)
+		}
+		self.depth--
+		return rule, nil
+	}
+	return self.parseRule()
+}
+
+//解析单个验证器，如 required、string=1,5、in='a,b','c'
+func (self *ruleParser) parseRule() (*rulePlan, error) {
+	start := self.pos
+	for self.pos < len(self.tag) && !self.isDelimiter(self.pos) && !self.peek(VALIDATOR_VALUE_SIGN) {
+		self.pos++
+	}
+	rule := &rulePlan{key: self.tag[start:self.pos]}
+	if strings.ContainsAny(rule.key, VALIDATOR_NOT+VALIDATOR_GROUP_BEGIN+VALIDATOR_GROUP_END+VALIDATOR_QUOTES) {
+		self.pos = start
+		return nil, self.errorf("unexpected %q in %v", rule.key, rule.key)
+	}
+	//含有赋值符号时解析参数，如 array=1,2
+	if self.consume(VALIDATOR_VALUE_SIGN) {
+		for {
+			arg, err := self.parseArg()
+			if err != nil {
+				return nil, err
+			}
+			rule.args = append(rule.args, arg)
+			if !self.consume(VALIDATOR_RANGE_SPLIT) {
+				break
+			}
+		}
+	}
+	rule.required = requiredValidators[rule.key]
+	//omitempty、omitnil、dive、keys、endkeys 由验证流程处理，不需要验证器
+	if !flowRules[rule.key] {
+		rule.validator, rule.copy, rule.err = self.validator.resolveValidator(rule.key)
+	}
+	return rule, nil
+}
+
+//解析单个参数，参数以 ' 或 " 开头时，到对应的引号结束，可以包含 ,、|、|| 等字符，引号中的 \' \" \\ 为转义
+//不带引号的参数到 ,、分隔符、右括号(在括号中时)结束，| 后边是验证器时才作为或结束参数，否则作为参数的一部分，如 in=a|b、reg=^(a|b)$
+//\ 后边为这些字符或 \ 时为转义，其他情况 \ 保持原样，如正则中的 \d
+func (self *ruleParser) parseArg() (string, error) {
+	var arg strings.Builder
+	if self.pos < len(self.tag) && strings.ContainsRune(VALIDATOR_QUOTES, rune(self.tag[self.pos])) {
+		start := self.pos
+		quote := self.tag[self.pos]
+		self.pos++
+		for {
+			if self.pos >= len(self.tag) {
+				self.pos = start
+				return "", self.errorf("unterminated quoted argument")
+			}
+			c := self.tag[self.pos]
+			if c == quote {
+				self.pos++
+				break
+			}
+			if c == '\\' && self.pos+1 < len(self.tag) && (self.tag[self.pos+1] == quote || self.tag[self.pos+1] == '\\') {
+				self.pos++
+				c = self.tag[self.pos]
+			}
+			arg.WriteByte(c)
+			self.pos++
+		}
+		if self.pos < len(self.tag) && !self.isDelimiter(self.pos) && !self.peek(VALIDATOR_RANGE_SPLIT) {
+			return "", self.errorf("unexpected %q after quoted argument", self.tag[self.pos:self.pos+1])
+		}
+		return arg.String(), nil
+	}
+	for self.pos < len(self.tag) && !self.isArgEnd(self.pos) && !self.peek(VALIDATOR_RANGE_SPLIT) {
+		if next := self.pos + 1; self.peek("\\") && next < len(self.tag) {
+			if self.isDelimiter(next) || strings.HasPrefix(self.tag[next:], VALIDATOR_RANGE_SPLIT) || strings.ContainsRune(VALIDATOR_QUOTES+"\\", rune(self.tag[next])) {
+				self.pos = next
+			}
+		}
+		arg.WriteByte(self.tag[self.pos])
+		self.pos++
+	}
+	return arg.String(), nil
+}
+
+//判断 i 处是否为验证器的结束位置，即分隔符、|、右括号(在括号中时)
+func (self *ruleParser) isDelimiter(i int) bool {
+	str := self.tag[i:]
+	return strings.HasPrefix(str, self.validator.validatorSplit) || strings.HasPrefix(str, VALIDATOR_OR) || (self.depth > 0 && strings.HasPrefix(str, VALIDATOR_GROUP_END))
+}
+
+//判断不带引号的参数是否在 i 处结束，| 后边是 !、( 或者注册的验证器时才作为或，兼容参数中直接使用 | 的 tag
+func (self *ruleParser) isArgEnd(i int) bool {
+	if !self.isDelimiter(i) {
+		return false
+	}
+	if strings.HasPrefix(self.tag[i:], self.validator.validatorSplit) || !strings.HasPrefix(self.tag[i:], VALIDATOR_OR) {
+		return true
+	}
+	start := i + len(VALIDATOR_OR)
+	if start < len(self.tag) && strings.ContainsRune(VALIDATOR_NOT+VALIDATOR_GROUP_BEGIN, rune(self.tag[start])) {
+		return true
+	}
+	end := start
+	for end < len(self.tag) && !self.isDelimiter(end) && !strings.HasPrefix(self.tag[end:], VALIDATOR_VALUE_SIGN) {
+		end++
+	}
+	key := self.tag[start:end]
+	if flowRules[key] {
+		return true
+	}
+	_, ok := self.validator.validator[key]
+	return ok
+}
+
+func (self *ruleParser) peek(str string) bool {
+	return str != "" && strings.HasPrefix(self.tag[self.pos:], str)
+}
+
+func (self *ruleParser) consume(str string) bool {
+	if !self.peek(str) {
+		return false
+	}
+	self.pos += len(str)
+	return true
+}
+
+func (self *ruleParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("validator tag %v error at %v: %v", self.tag, self.pos, fmt.Sprintf(format, args...))
+}
+
+//组合多个验证器，只有一个验证器时(非运算除外)直接返回该验证器
+func (self *ruleParser) combineRules(op string, items []*rulePlan) *rulePlan {
+	if len(items) == 1 && op != VALIDATOR_NOT {
+		return items[0]
+	}
+	keys := make([]string, 0, len(items))
+	rule := &rulePlan{op: op, items: items}
+	for _, item := range items {
+		key := item.String()
+		if item.op != "" && item.op != VALIDATOR_NOT {
+			key = VALIDATOR_GROUP_BEGIN + key + VALIDATOR_GROUP_END
+		}
+		keys = append(keys, key)
+		//组合中不能使用 omitempty、dive 等流程控制的 tag
+		if rule.err == nil && flowRules[item.key] && item.op == "" {
+			rule.err = fmt.Errorf("validator %v can not be combined", item.key)
+		}
+		if rule.err == nil {
+			rule.err = item.err
+		}
+	}
+	switch op {
+	case VALIDATOR_NOT:
+		rule.key = VALIDATOR_NOT + keys[0]
+	case VALIDATOR_OR:
+		rule.key = strings.Join(keys, VALIDATOR_OR)
+	default:
+		rule.key = strings.Join(keys, self.validator.validatorSplit)
+	}
+	return rule
+}
+
+//参数的字符串形式，含有 ,、|、括号、引号时加引号，和 parseArg 的解析规则一致
+func quoteArg(arg string) string {
+	if !strings.ContainsAny(arg, VALIDATOR_RANGE_SPLIT+VALIDATOR_OR+VALIDATOR_GROUP_BEGIN+VALIDATOR_GROUP_END+VALIDATOR_QUOTES) {
+		return arg
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(arg) + "'"
+}
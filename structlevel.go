@@ -0,0 +1,105 @@
+package govalidators
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//struct 级别的验证器，用于多个字段之间的联合验证，如至少填写一种联系方式
+//val 为 struct 的值，返回的错误可以通过 sl.ReportError 关联到具体字段
+type StructValidatorF func(sl *StructLevel, val reflect.Value) []error
+
+//struct 级别验证器的上下文
+type StructLevel struct {
+	validator *goValidator
+	current   reflect.Value
+	path      string
+}
+
+//当前验证的 struct
+func (self *StructLevel) Current() reflect.Value {
+	return self.current
+}
+
+//当前验证的 struct 的路径，顶层 struct 为空
+func (self *StructLevel) Path() string {
+	return self.path
+}
+
+//生成字段的验证错误，field 为 . 分隔的 struct 字段名，如 Guardian、Contact.Email
+//msg 中的 [name] 会替换为字段名，设置了 title 时为 title 的值，错误路径和字段验证器的错误路径一致
+func (self *StructLevel) ReportError(field, rule, msg string) *ValidationError {
+	path := self.path
+	name := field
+	typeObj := self.current.Type()
+	for _, fieldName := range strings.Split(field, ".") {
+		for typeObj != nil && typeObj.Kind() == reflect.Ptr {
+			typeObj = typeObj.Elem()
+		}
+		var plan *fieldPlan
+		if typeObj != nil && typeObj.Kind() == reflect.Struct {
+			plan = self.validator.getStructPlan(typeObj).getField(fieldName)
+		}
+		if plan == nil {
+			path = joinPath(path, fieldName)
+			name = fieldName
+			typeObj = nil
+			continue
+		}
+		path = joinPath(path, plan.pathName)
+		name = plan.name
+		typeObj = plan.field.Type
+	}
+	val, _ := lookupField(self.current, field)
+	err := formatError(msg, map[string]string{"name": name})
+	return toValidationError(err, name, path, rule, nil, val)
+}
+
+//执行 struct 级别的验证器，验证器返回普通 error 时，错误关联到 struct 本身
+func (self *goValidator) validateStruct(validators []StructValidatorF, val reflect.Value, path string, params *itemParams) (returnErr []error) {
+	//通过未导出字段获取的值无法调用 Interface()，不执行
+	if !val.CanInterface() {
+		return
+	}
+	sl := &StructLevel{
+		validator: self,
+		current:   val,
+		path:      path,
+	}
+	for _, validator := range validators {
+		for _, err := range validator(sl, val) {
+			if err == nil {
+				continue
+			}
+			returnErr = append(returnErr, toValidationError(err, val.Type().Name(), path, VALIDATOR_STRUCT, nil, val))
+			if params.lazyFlag {
+				return
+			}
+		}
+	}
+	return
+}
+
+//注册 struct 级别的验证器，sample 为 struct 或 struct 指针，如 Student{}、&Student{}
+//验证器在该类型的字段验证器之后执行，同一类型可以注册多个，嵌套在 slice、map 中的 struct 同样生效
+func (self *goValidator) RegisterStructValidator(sample interface{}, validator StructValidatorF) error {
+	typeObj := reflect.TypeOf(sample)
+	for typeObj != nil && typeObj.Kind() == reflect.Ptr {
+		typeObj = typeObj.Elem()
+	}
+	if typeObj == nil || typeObj.Kind() != reflect.Struct {
+		return fmt.Errorf("struct validator sample %T is not a struct", sample)
+	}
+	if validator == nil {
+		return fmt.Errorf("struct validator of %v is nil", typeObj)
+	}
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.structValidators == nil {
+		self.structValidators = make(map[reflect.Type][]StructValidatorF)
+	}
+	self.structValidators[typeObj] = append(self.structValidators[typeObj], validator)
+	self.resetPlanCache()
+	return nil
+}
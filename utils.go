@@ -1,12 +1,19 @@
 package govalidators
 
 import (
-	"errors"
+	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+var timeType = reflect.TypeOf(time.Time{})
+
 type Kind uint
 
 const (
@@ -20,6 +27,29 @@ const (
 	BOOL_KIND
 )
 
+//预编译的正则
+var (
+	mailRegexp    = regexp.MustCompile(MAIL_REG)
+	urlRegexp     = regexp.MustCompile(URL_REG)
+	integerRegexp = regexp.MustCompile(INTEGER_REG)
+	floatRegexp   = regexp.MustCompile(FLOAT_REG)
+	//路径中的下标，如 [0]、["math"]
+	pathIndexRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+)
+
+//datetime 格式转换为正则的替换规则
+var dateTimeReplacer = strings.NewReplacer(
+	"Y", YEAR_REG, "m", MONTH_REF, "d", DAY_REF, "H", HOUR_REF, "i", MINUTE_REF, "s", SECOND_REF,
+)
+
+//自定义正则和 datetime 格式编译后的缓存，key 为正则字符串
+var regexpCache sync.Map
+
+//带校验方法的验证器，SetValidator 时调用，提前发现错误配置，如不合法的自定义正则
+type regChecker interface {
+	checkReg() error
+}
+
 //判断是否为 array、map、slice 的 map
 var arrayMap = map[reflect.Kind]Kind{
 	reflect.Array: ARRAY_KIND,
@@ -91,6 +121,20 @@ func checkNumber(v interface{}, args ...interface{}) (ok bool) {
 	return
 }
 
+//判断 float 是否为整数，NaN、±Inf 不是整数
+func isWholeFloat(num float64) bool {
+	return !math.IsInf(num, 0) && math.Trunc(num) == num
+}
+
+//判断是否为无符号整数
+func isUint(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
 //val is kind or val
 func checkArray(v interface{}, args ...interface{}) (ok bool) {
 	var t Kind = ALL_KIND
@@ -120,11 +164,15 @@ func checkArrayValueIsMulti(value reflect.Value) (ok bool, fieldNum int) {
 	if !ok {
 		return
 	}
-	//检查值的类型是不是 map、array、map 或 struct
+	//检查值的类型是不是 map、array、map、struct、interface 或实现了 Validatable 接口，interface 的动态类型在递归时判断
+	//指针类型的值按指向的类型判断
 	valueKind := value.Type().Elem().Kind()
+	for elemType := value.Type().Elem(); elemType.Kind() == reflect.Ptr; elemType = elemType.Elem() {
+		valueKind = elemType.Elem().Kind()
+	}
 
 	ok = checkArray(valueKind)
-	if !ok && valueKind != reflect.Struct {
+	if !ok && valueKind != reflect.Struct && valueKind != reflect.Interface && !isValidatable(value.Type().Elem()) {
 		return
 	}
 	fieldNum = value.Len()
@@ -135,6 +183,27 @@ func checkArrayValueIsMulti(value reflect.Value) (ok bool, fieldNum int) {
 	return
 }
 
+//判断类型是否实现了 Validatable 接口，包括指针接收者实现的情况
+func isValidatable(typeObj reflect.Type) bool {
+	return typeObj.Implements(validatableT) || (typeObj.Kind() != reflect.Ptr && reflect.PtrTo(typeObj).Implements(validatableT))
+}
+
+//获取值实现的 Validatable 接口，值不可寻址时，指针接收者的方法通过值的拷贝调用
+func asValidatable(val reflect.Value) (Validatable, bool) {
+	if !val.IsValid() || !val.CanInterface() || !isValidatable(val.Type()) {
+		return nil, false
+	}
+	if validatable, ok := val.Interface().(Validatable); ok {
+		return validatable, true
+	}
+	if !val.CanAddr() {
+		tmpVal := reflect.New(val.Type())
+		tmpVal.Elem().Set(val)
+		val = tmpVal.Elem()
+	}
+	return val.Addr().Interface().(Validatable), true
+}
+
 func InArray(val interface{}, listArr interface{}) (re bool) {
 	lv := reflect.ValueOf(listArr)
 	l := lv.Len()
@@ -147,9 +216,48 @@ func InArray(val interface{}, listArr interface{}) (re bool) {
 	return
 }
 
+//获取指针、interface 指向的值，支持多级指针，遇到 nil 指针或 nil interface 时返回该值，isNil 为 true
+func indirectValue(val reflect.Value) (re reflect.Value, isNil bool) {
+	re = val
+	for re.Kind() == reflect.Ptr || re.Kind() == reflect.Interface {
+		if re.IsNil() {
+			return re, true
+		}
+		re = re.Elem()
+	}
+	return
+}
+
+//判断值是否为非 nil 的指针(包括 interface 中的指针)，指针指向零值时同样视为已设置，如 PATCH 请求中传了 0
+func isPresentPtr(val reflect.Value) bool {
+	for val.Kind() == reflect.Interface && !val.IsNil() {
+		val = val.Elem()
+	}
+	return val.Kind() == reflect.Ptr && !val.IsNil()
+}
+
+//required 类验证器判断值是否未设置，通过非 nil 指针获取的值(params["present"] 为 true)视为已设置
+func isAbsentValue(params map[string]interface{}, val reflect.Value) bool {
+	if present, _ := params["present"].(bool); present {
+		return false
+	}
+	return isZeroValue(val)
+}
+
+//判断值是否为 nil，仅 chan、func、interface、map、ptr、slice 类型可以为 nil
+func isNilValue(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return val.IsNil()
+	}
+	return false
+}
+
 func isZeroValue(val reflect.Value) bool {
 	typeKind := val.Kind()
 	switch typeKind {
+	case reflect.Invalid:
+		return true
 	case reflect.String, reflect.Array:
 		return val.Len() == 0
 	case reflect.Map, reflect.Slice:
@@ -165,16 +273,37 @@ func isZeroValue(val reflect.Value) bool {
 	case reflect.Interface, reflect.Ptr:
 		return val.IsNil()
 	}
-	return reflect.DeepEqual(val.Interface(), reflect.Zero(val.Type()).Interface())
+	return val.IsZero()
 }
 
-func formatError(format string, eParamsMap map[string]string) error {
+func formatError(format string, eParamsMap map[string]string) *ValidationError {
 	var params []string
 	for k, v := range eParamsMap {
 		params = append(params, "["+k+"]", v)
 	}
 	replacer := strings.NewReplacer(params...)
-	return errors.New(replacer.Replace(format))
+	return &ValidationError{
+		Field: eParamsMap["name"],
+		Msg:   replacer.Replace(format),
+	}
+}
+
+//生成验证器配置错误，errors.Is(err, ErrValidatorConfig) 为 true
+func formatConfigError(format string, eParamsMap map[string]string) *ValidationError {
+	err := formatError(format, eParamsMap)
+	err.err = ErrValidatorConfig
+	return err
+}
+
+//获取 reflect.Value 的值，未导出字段无法调用 Interface()，基础类型通过反射取值，其他类型返回 nil
+func valueInterface(val reflect.Value) interface{} {
+	if !val.IsValid() {
+		return nil
+	}
+	if val.CanInterface() {
+		return val.Interface()
+	}
+	return parseReflectV(val, val.Kind())
 }
 
 func parseStr(val string, kind reflect.Kind) (re interface{}, err error) {
@@ -208,3 +337,220 @@ func parseReflectV(val reflect.Value, kind reflect.Kind) (re interface{}) {
 	}
 	return
 }
+
+//拼接下标路径，如 Class[1]、Scores["math"]
+func indexPath(path, index string) string {
+	return path + "[" + index + "]"
+}
+
+//格式化 map 的 key，字符串类型的 key 会加上双引号
+func formatMapKey(key reflect.Value) string {
+	switch key.Kind() {
+	case reflect.String:
+		return strconv.Quote(key.String())
+	case reflect.Interface, reflect.Ptr:
+		if key.IsNil() {
+			return "nil"
+		}
+		return formatMapKey(key.Elem())
+	}
+	return fmt.Sprintf("%v", key)
+}
+
+//去掉路径中的下标，如 Class[0].Cname 转为 Class.Cname
+func trimPathIndex(path string) string {
+	return pathIndexRegexp.ReplaceAllString(path, "")
+}
+
+//判断路径或其上级路径是否在 paths 中，如 Class.Cname 的上级路径为 Class
+func matchPathPrefix(paths map[string]bool, path string) bool {
+	for {
+		if paths[path] {
+			return true
+		}
+		num := strings.LastIndex(path, ".")
+		if num == -1 {
+			return false
+		}
+		path = path[:num]
+	}
+}
+
+//拼接字段路径，如 Class.Cname
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+//检查验证器配置是否正确
+func checkValidator(validatorK string, validator interface{}) error {
+	if checker, ok := validator.(regChecker); ok {
+		if err := checker.checkReg(); err != nil {
+			return fmt.Errorf("validator %v reg error: %v", validatorK, err)
+		}
+	}
+	return nil
+}
+
+//编译正则，编译结果会被缓存
+func compileRegexp(reg string) (*regexp.Regexp, error) {
+	if cacheReg, ok := regexpCache.Load(reg); ok {
+		return cacheReg.(*regexp.Regexp), nil
+	}
+	compiledReg, err := regexp.Compile(reg)
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.Store(reg, compiledReg)
+	return compiledReg, nil
+}
+
+//将 datetime 格式(如 Y-m-d H:i:s)转换为正则并编译
+func dateTimeRegexp(fmtStr string) (*regexp.Regexp, error) {
+	return compileRegexp(`^` + dateTimeReplacer.Replace(fmtStr) + `$`)
+}
+
+//判断值是否等于字符串表示的值，字符串会按值的类型转换，如 equalString(reflect.ValueOf(1), "01") 为 true
+func equalString(val reflect.Value, str string) bool {
+	val, isNil := indirectValue(val)
+	if isNil || !val.IsValid() {
+		return false
+	}
+	kind := val.Kind()
+	if !checkBool(kind) && !checkNumber(kind) && !checkString(kind) {
+		return fmt.Sprintf("%v", val) == str
+	}
+	tmpStr, err := parseStr(str, kind)
+	if err != nil {
+		return false
+	}
+	return parseReflectV(val, kind) == tmpStr
+}
+
+//根据 . 分隔的字段路径获取 struct 中的字段或 map[string] 中的值，如 Period.BeginTime
+//路径中的 nil 指针(包括嵌入的 struct 指针)视为零值，继续在零值中查找，字段不存在时返回 false
+func lookupField(parent reflect.Value, path string) (re reflect.Value, ok bool) {
+	//没有所在的 struct 或 map(如 ValidateVar)时，字段不存在
+	if !parent.IsValid() {
+		return parent, false
+	}
+	re = parent
+	for _, name := range strings.Split(path, ".") {
+		//ValidateMap 中，map 的 key 不存在时视为零值
+		if !re.IsValid() {
+			return re, true
+		}
+		re = indirectZero(re)
+		switch re.Kind() {
+		case reflect.Struct:
+			field, found := re.Type().FieldByName(name)
+			if !found {
+				return reflect.Value{}, false
+			}
+			//逐层获取提升的字段，不使用 FieldByName，嵌入的 struct 指针为 nil 时 FieldByName 会 panic
+			for i, index := range field.Index {
+				if i > 0 {
+					re = indirectZero(re)
+				}
+				re = re.Field(index)
+			}
+		case reflect.Map:
+			if re.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, false
+			}
+			re = re.MapIndex(reflect.ValueOf(name).Convert(re.Type().Key()))
+			if re.Kind() == reflect.Interface && !re.IsNil() {
+				re = re.Elem()
+			}
+		case reflect.Invalid:
+			//nil interface 无法确定类型，视为零值
+			return re, true
+		default:
+			return reflect.Value{}, false
+		}
+	}
+	return re, true
+}
+
+//获取指针、interface 指向的值，nil 指针返回指向类型的零值，nil interface 返回无效的值
+func indirectZero(val reflect.Value) reflect.Value {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		switch {
+		case !val.IsNil():
+			val = val.Elem()
+		case val.Kind() == reflect.Ptr:
+			val = reflect.Zero(val.Type().Elem())
+		default:
+			return reflect.Value{}
+		}
+	}
+	return val
+}
+
+//比较两个值的大小，a < b 返回 -1，a == b 返回 0，a > b 返回 1
+//支持数字、字符串、bool、time.Time，类型不支持比较时 ok 为 false
+func compareValue(a, b reflect.Value) (cmp int, ok bool) {
+	var aNil, bNil bool
+	a, aNil = indirectValue(a)
+	b, bNil = indirectValue(b)
+	if aNil || bNil || !a.IsValid() || !b.IsValid() {
+		return
+	}
+	aKind, bKind := a.Kind(), b.Kind()
+	switch {
+	case checkNumber(aKind) && checkNumber(bKind):
+		if checkNumber(aKind, FLOAT_KIND) || checkNumber(bKind, FLOAT_KIND) {
+			aFloat, bFloat := numberToFloat(a), numberToFloat(b)
+			if math.IsNaN(aFloat) || math.IsNaN(bFloat) {
+				return
+			}
+			if aFloat < bFloat {
+				return -1, true
+			}
+			if aFloat > bFloat {
+				return 1, true
+			}
+			return 0, true
+		}
+		return numberToBigInt(a).Cmp(numberToBigInt(b)), true
+	case checkString(aKind) && checkString(bKind):
+		return strings.Compare(a.String(), b.String()), true
+	case checkBool(aKind) && checkBool(bKind):
+		if a.Bool() == b.Bool() {
+			return 0, true
+		}
+		if b.Bool() {
+			return -1, true
+		}
+		return 1, true
+	case a.Type() == timeType && b.Type() == timeType && a.CanInterface() && b.CanInterface():
+		aTime, bTime := a.Interface().(time.Time), b.Interface().(time.Time)
+		if aTime.Before(bTime) {
+			return -1, true
+		}
+		if aTime.After(bTime) {
+			return 1, true
+		}
+		return 0, true
+	}
+	return
+}
+
+func numberToFloat(val reflect.Value) float64 {
+	if checkNumber(val.Kind(), FLOAT_KIND) {
+		return val.Float()
+	}
+	if isUint(val.Kind()) {
+		return float64(val.Uint())
+	}
+	return float64(val.Int())
+}
+
+func numberToBigInt(val reflect.Value) *big.Int {
+	if isUint(val.Kind()) {
+		return new(big.Int).SetUint64(val.Uint())
+	}
+	return big.NewInt(val.Int())
+}
@@ -1,11 +1,31 @@
 package govalidators
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
+//判断错误的路径和 expected 一致，name、param 用于输出失败的用例
+func assertPaths(t *testing.T, errs []error, expected []string, name string, param interface{}) {
+	t.Helper()
+	if len(errs) != len(expected) {
+		t.Errorf("Expected %v %+v,paths %v,err %v", name, param, expected, errs)
+		return
+	}
+	for i, e := range errs {
+		if vErr, ok := e.(*ValidationError); !ok || vErr.Path != expected[i] {
+			t.Errorf("Expected %v %+v,path %v,err %v", name, param, expected[i], e)
+		}
+	}
+}
+
 func TestRequired(t *testing.T) {
 	validator := New()
 
@@ -593,3 +613,1549 @@ func TestMuti1(t *testing.T) {
 		}
 	}
 }
+
+type errClass struct {
+	BeginTime string `validate:"required||datetime=H:i"`
+}
+
+type errStudent struct {
+	Name  string     `validate:"required||string=1,5" title:"姓名"`
+	Sex   string     `validate:"required||in=male,female"`
+	Class []errClass `validate:"array=_,3"`
+}
+
+func TestValidationError(t *testing.T) {
+	validator := New()
+	testErr := []struct {
+		param    *errStudent
+		path     string
+		field    string
+		rule     string
+		args     []string
+		expected interface{}
+	}{
+		{&errStudent{Name: "张三张三张三", Sex: "male"}, "Name", "姓名", "string", []string{"1", "5"}, "张三张三张三"},
+		{&errStudent{Name: "张三", Sex: "man"}, "Sex", "Sex", "in", []string{"male", "female"}, "man"},
+		{&errStudent{Name: "张三", Sex: "male", Class: []errClass{{"13:00"}, {"25:00"}}}, "Class[1].BeginTime", "BeginTime", "datetime", []string{"H:i"}, "25:00"},
+	}
+	for _, test := range testErr {
+		err := validator.LazyValidate(test.param)
+		vErr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("Expected *ValidationError,err %v", err)
+			continue
+		}
+		if vErr.Path != test.path || vErr.Field != test.field || vErr.Rule != test.rule ||
+			!reflect.DeepEqual(vErr.Args, test.args) || !reflect.DeepEqual(vErr.Value, test.expected) {
+			t.Errorf("Expected validation error %v,got %+v", test.path, vErr)
+		}
+	}
+}
+
+type errConfigEmpty struct{}
+
+type errConfigStudent struct {
+	Name  string            `validate:"notexist" title:"姓名"`
+	Tags  map[string]string `validate:"keys"`
+	Bad   string            `validate:"email|(url"`
+	Empty errConfigEmpty
+}
+
+func TestConfigValidationError(t *testing.T) {
+	validator := New().SetSkipOnStructEmpty(false)
+	testErr := []struct {
+		path string
+		rule string
+		msg  string
+	}{
+		{"Name", "notexist", "validator notexist not exist"},
+		{"Tags", "keys", "validator keys must follow dive"},
+		{"Bad", "email|(url", "validator tag email|(url error at 10: missing \")\""},
+		{"Empty", "struct", "struct errConfigEmpty is empty"},
+	}
+	err := validator.Validate(&errConfigStudent{Name: "张三", Tags: map[string]string{"a": "b"}, Bad: "a"})
+	if len(err) != len(testErr) {
+		t.Fatalf("Expected config errors,err %v", err)
+	}
+	for i, test := range testErr {
+		vErr, ok := err[i].(*ValidationError)
+		if !ok || vErr.Path != test.path || vErr.Rule != test.rule || vErr.Msg != test.msg {
+			t.Errorf("Expected config error %v,got %+v", test.path, err[i])
+		}
+	}
+}
+
+type pathScore struct {
+	Score int64 `validate:"integer=0,100" json:"score" title:"分数"`
+}
+
+type pathClass struct {
+	Cname string `validate:"string=1,5" json:"cname,omitempty" title:"课程名"`
+}
+
+type pathStudent struct {
+	Class  []pathClass          `json:"class"`
+	Scores map[string]pathScore `json:"scores"`
+	Ranks  map[int64]pathScore  `json:"-"`
+}
+
+func TestFieldPath(t *testing.T) {
+	student := &pathStudent{
+		Class:  []pathClass{{"语文"}, {"数学"}, {"123456"}},
+		Scores: map[string]pathScore{"math": {101}},
+		Ranks:  map[int64]pathScore{3: {-1}},
+	}
+	testPath := []struct {
+		nameFunc FieldNameFunc
+		expected []string
+	}{
+		{nil, []string{"Class[2].Cname", `Scores["math"].Score`, "Ranks[3].Score"}},
+		{StructFieldName, []string{"Class[2].Cname", `Scores["math"].Score`, "Ranks[3].Score"}},
+		{JsonFieldName, []string{"class[2].cname", `scores["math"].score`, "Ranks[3].score"}},
+		{TagFieldName("title"), []string{"Class[2].课程名", `Scores["math"].分数`, "Ranks[3].分数"}},
+	}
+	for _, test := range testPath {
+		validator := New().SetFieldNameFunc(test.nameFunc)
+		var paths []string
+		for _, err := range validator.Validate(student) {
+			paths = append(paths, err.(*ValidationError).Path)
+		}
+		if !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("Expected path %v,got %v", test.expected, paths)
+		}
+	}
+}
+
+type cacheT struct {
+	Name string `validate:"string=1,5||cacheCheck" check:"required"`
+}
+
+func TestPlanCache(t *testing.T) {
+	validator := New()
+	test := &cacheT{Name: "abc"}
+	if err := validator.Validate(test); len(err) != 1 || err[0].Error() != "validator cacheCheck not exist" {
+		t.Errorf("Expected cache,err %v", err)
+	}
+	validator.SetValidator("cacheCheck", func(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+		return false, fmt.Errorf("%v is invalid", params["name"])
+	})
+	if err := validator.Validate(test); len(err) != 1 || err[0].Error() != "Name is invalid" {
+		t.Errorf("Expected cache reset after SetValidator,err %v", err)
+	}
+	validator.SetTag("check")
+	if err := validator.Validate(&cacheT{}); len(err) != 1 || err[0].(*ValidationError).Rule != "required" {
+		t.Errorf("Expected cache reset after SetTag,err %v", err)
+	}
+	validator.SetTag("validate").SetValidatorSplit("|")
+	if err := validator.Validate(test); len(err) != 2 || err[0].Error() != "validator  not exist" {
+		t.Errorf("Expected cache reset after SetValidatorSplit,err %v", err)
+	}
+}
+
+func TestCustomReg(t *testing.T) {
+	validator := New()
+	testReg := []struct {
+		validator interface{}
+		expected  bool
+	}{
+		{&EmailValidator{Reg: `^(\d)+$`}, true},
+		{&EmailValidator{Reg: `^(\d+$`}, false},
+		{&UrlValidator{Reg: `^https://[a-z.]+$`}, true},
+		{&UrlValidator{Reg: `^https://[a-z.+$`}, false},
+		{&DateTimeValidator{FmtStr: "Y-m-d"}, true},
+		{&DateTimeValidator{FmtStr: "(Y-m-d"}, false},
+	}
+	for _, test := range testReg {
+		err := validator.SetValidator("regCheck", test.validator)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected reg,value %+v,err %v", test.validator, err)
+		}
+	}
+	if err := validator.SetValidators(map[string]interface{}{
+		"digits": &EmailValidator{Reg: `^(\d)+$`},
+		"bad":    &EmailValidator{Reg: `(`},
+	}); err == nil {
+		t.Errorf("Expected reg error")
+	}
+	testNotSet := struct {
+		param string `validate:"digits"`
+	}{"123"}
+	if err := validator.Validate(testNotSet); len(err) != 1 || err[0].Error() != "validator digits not exist" {
+		t.Errorf("Expected reg,err %v", err)
+	}
+
+	testDigits := []struct {
+		param    string `validate:"regCheck"`
+		expected bool
+	}{
+		{"2018-03-03", true},
+		{"2018-13-03", false},
+		{"2018-03-03 10:00:00", false},
+	}
+	for _, test := range testDigits {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected reg,value %v,err %v", test.param, err)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	validator1 := New()
+	validator2 := New()
+	validator1.SetValidator("vm", func(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+		return true, nil
+	})
+	validator2.SetValidator("vm", func(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+		return false, fmt.Errorf("%v is invalid", params["name"])
+	})
+	test := struct {
+		Name string `validate:"vm"`
+	}{"abc"}
+	if err := validator1.Validate(test); err != nil {
+		t.Errorf("Expected registry,err %v", err)
+	}
+	if err := validator2.Validate(test); len(err) != 1 {
+		t.Errorf("Expected registry,err %v", err)
+	}
+	if New().HasValidator("vm") || !validator1.HasValidator("vm") || !validator1.HasValidator("required") {
+		t.Errorf("Expected registry,validators %v", validator1.Validators())
+	}
+	validator1.RemoveValidator("vm")
+	if err := validator1.Validate(test); len(err) != 1 || err[0].Error() != "validator vm not exist" {
+		t.Errorf("Expected registry,err %v", err)
+	}
+	if !reflect.DeepEqual(validator1.Validators(), New().Validators()) {
+		t.Errorf("Expected registry,validators %v", validator1.Validators())
+	}
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			validator2.SetValidator(fmt.Sprintf("vm%v", i), userMethod)
+		}
+		done <- true
+	}()
+	for i := 0; i < 100; i++ {
+		validator2.Validate(test)
+	}
+	<-done
+}
+
+func TestFloat(t *testing.T) {
+	validator := New()
+	testBetween := []struct {
+		param    float64 `validate:"float=-1.5,20"`
+		expected bool
+	}{
+		{-1.5, true},
+		{15.25, true},
+		{20, true},
+		{-1.51, false},
+		{20.01, false},
+		{math.NaN(), false},
+		{math.Inf(1), false},
+	}
+	for _, test := range testBetween {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected float,value %v,err %v", test.param, err)
+		}
+	}
+
+	testAtLeast := []struct {
+		param    float32 `validate:"float=0.5,_"`
+		expected bool
+	}{
+		{0.5, true},
+		{1000, true},
+		{0.49, false},
+	}
+	for _, test := range testAtLeast {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected float,value %v,err %v", test.param, err)
+		}
+	}
+
+	testLessThan := []struct {
+		param    float64 `validate:"float=_,0.5"`
+		expected bool
+	}{
+		{-100, true},
+		{0.5, true},
+		{0.51, false},
+	}
+	for _, test := range testLessThan {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected float,value %v,err %v", test.param, err)
+		}
+	}
+
+	//float32 的值按 float32 精度比较范围
+	testFloat32 := []struct {
+		value    float32
+		tag      string
+		expected bool
+	}{
+		{0.1, "float=0,0.1", true},
+		{0.3, "float=0.3", true},
+		{0.1, "float=0.1,_", true},
+		{0.1, "number=0,0.1", true},
+		{0.10000001, "float=0,0.1", false},
+		{0.29999998, "float=0.3", false},
+		{0.099999994, "float=0.1,_", false},
+	}
+	for _, test := range testFloat32 {
+		err := validator.ValidateVar(test.value, test.tag)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected float32,value %v %v,err %v", test.value, test.tag, err)
+		}
+	}
+
+	testType := []struct {
+		param    int64 `validate:"float"`
+		expected bool
+	}{
+		{1, false},
+	}
+	for _, test := range testType {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected float,value %v,err %v", test.param, err)
+		}
+	}
+
+	validator.SetValidator("float", &FloatValidator{
+		Range: Range{
+			RangeEMsg: map[string]string{
+				"between": "[name] 必须在 [min] 和 [max] 之间",
+			},
+		},
+	})
+	testEMsg := struct {
+		Price float64 `validate:"float=0.01,99.99" title:"价格"`
+	}{100}
+	if err := validator.LazyValidate(testEMsg); err == nil || err.Error() != "价格 必须在 0.01 和 99.99 之间" {
+		t.Errorf("Expected float,err %v", err)
+	}
+}
+
+func TestNumber(t *testing.T) {
+	validator := New()
+	testNumber := []struct {
+		Int      int64   `validate:"number=1,100"`
+		Float    float64 `validate:"number=0,99.5"`
+		expected bool
+	}{
+		{1, 0, true},
+		{100, 99.5, true},
+		{0, 50, false},
+		{50, 99.6, false},
+		{50, math.Inf(-1), false},
+	}
+	for _, test := range testNumber {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected number,value %v %v,err %v", test.Int, test.Float, err)
+		}
+	}
+
+	testType := []struct {
+		param    string `validate:"number"`
+		expected bool
+	}{
+		{"1", false},
+	}
+	for _, test := range testType {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected number,value %v,err %v", test.param, err)
+		}
+	}
+}
+
+func TestUint(t *testing.T) {
+	validator := New()
+	testAtLeast := []struct {
+		param    uint64 `validate:"integer=1,_"`
+		expected bool
+	}{
+		{1, true},
+		{math.MaxUint64, true},
+		{0, false},
+	}
+	for _, test := range testAtLeast {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected uint,value %v,err %v", test.param, err)
+		}
+	}
+
+	testBetween := []struct {
+		param    uint64 `validate:"integer=9223372036854775808,18446744073709551614"`
+		expected bool
+	}{
+		{9223372036854775808, true},
+		{18446744073709551614, true},
+		{9223372036854775807, false},
+		{math.MaxUint64, false},
+	}
+	for _, test := range testBetween {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected uint,value %v,err %v", test.param, err)
+		}
+	}
+
+	testNegative := []struct {
+		param    uint8 `validate:"integer=-10,10"`
+		expected bool
+	}{
+		{0, true},
+		{10, true},
+		{11, false},
+	}
+	for _, test := range testNegative {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected uint,value %v,err %v", test.param, err)
+		}
+	}
+
+	testNumber := []struct {
+		param    uint32 `validate:"number=_,99.5"`
+		expected bool
+	}{
+		{99, true},
+		{100, false},
+	}
+	for _, test := range testNumber {
+		err := validator.Validate(test)
+		if (err != nil && test.expected == true) || (err == nil && test.expected != true) {
+			t.Errorf("Expected uint,value %v,err %v", test.param, err)
+		}
+	}
+}
+
+type ptrAddress struct {
+	City string `validate:"required||string=1,10"`
+}
+
+type ptrStudent struct {
+	Name    *string     `validate:"string=1,5"`
+	Age     **int64     `validate:"required||integer=10,30"`
+	Email   *string     `validate:"email"`
+	Uid     *uint64     `validate:"integer=1,_"`
+	Address *ptrAddress `validate:"required"`
+}
+
+type ptrPatch struct {
+	Count   *int64   `validate:"required"`
+	Name    *string  `validate:"required_with=Count"`
+	Enabled *bool    `validate:"required"`
+	Ages    []*int64 `validate:"dive||required"`
+	Age     **int64  `validate:"omitnil||required||integer=0,_"`
+}
+
+func TestPointer(t *testing.T) {
+	validator := New()
+	name, longName, email, badEmail := "张三", "张三张三张三", "123456@qq.com", "@qq.com"
+	age, badAge := int64(12), int64(31)
+	agePtr, badAgePtr := &age, &badAge
+	uid := uint64(1)
+	testPtr := []struct {
+		param    *ptrStudent
+		expected int
+	}{
+		{&ptrStudent{Name: &name, Age: &agePtr, Email: &email, Uid: &uid, Address: &ptrAddress{"北京"}}, 0},
+		{&ptrStudent{Age: &agePtr, Address: &ptrAddress{"北京"}}, 0},
+		{&ptrStudent{Name: &longName, Age: &badAgePtr, Email: &badEmail, Address: &ptrAddress{"北京"}}, 3},
+		{&ptrStudent{Address: &ptrAddress{"北京北京北京北京北京北京"}}, 2},
+		{&ptrStudent{Age: new(*int64)}, 2},
+	}
+	for _, test := range testPtr {
+		err := validator.Validate(test.param)
+		if len(err) != test.expected {
+			t.Errorf("Expected pointer,value %+v,err %v", test.param, err)
+		}
+	}
+	if err := validator.Validate((*ptrStudent)(nil)); err != nil {
+		t.Errorf("Expected pointer,err %v", err)
+	}
+
+	//指向零值的非 nil 指针视为已设置
+	zero, empty, disabled := int64(0), "", false
+	zeroPtr := &zero
+	testPatch := []struct {
+		param    *ptrPatch
+		expected []string
+	}{
+		{&ptrPatch{Count: &zero, Name: &empty, Enabled: &disabled, Ages: []*int64{&zero}}, nil},
+		{&ptrPatch{Count: &zero}, []string{"Name", "Enabled"}},
+		{&ptrPatch{Name: &empty, Enabled: &disabled, Ages: []*int64{nil}, Age: &zeroPtr}, []string{"Count", "Ages[0]"}},
+		{&ptrPatch{Name: &empty, Enabled: &disabled, Age: new(*int64)}, []string{"Count"}},
+	}
+	for _, test := range testPatch {
+		assertPaths(t, validator.Validate(test.param), test.expected, "pointer patch", test.param)
+	}
+	if err := validator.ValidateVar(&zero, "required"); err != nil {
+		t.Errorf("Expected pointer var,err %v", err)
+	}
+}
+
+type omitAddress struct {
+	City string `validate:"required"`
+}
+
+type omitT struct {
+	Email   string            `validate:"omitempty||email"`
+	Age     int64             `validate:"omitempty||integer=10,30"`
+	Phone   *string           `validate:"omitnil||string=11"`
+	Tags    []string          `validate:"omitnil||array=1,3"`
+	Extra   map[string]string `validate:"omitempty||array=1,_"`
+	Address omitAddress       `validate:"omitempty"`
+}
+
+func TestOmit(t *testing.T) {
+	phone, emptyPhone := "13800138000", ""
+	//expected 分别为 SetSkipOnStructEmpty(true)、SetSkipOnStructEmpty(false) 时的错误数
+	testOmit := []struct {
+		param    *omitT
+		expected [2]int
+	}{
+		{&omitT{}, [2]int{0, 0}},
+		{&omitT{Email: "123456@qq.com", Age: 20, Phone: &phone, Tags: []string{"a"}, Address: omitAddress{"北京"}}, [2]int{0, 0}},
+		{&omitT{Email: "qq.com", Age: 9}, [2]int{2, 2}},
+		{&omitT{Phone: &emptyPhone, Tags: []string{}}, [2]int{2, 0}},
+	}
+	for i, skip := range []bool{true, false} {
+		validator := New().SetSkipOnStructEmpty(skip)
+		for _, test := range testOmit {
+			err := validator.Validate(test.param)
+			if len(err) != test.expected[i] {
+				t.Errorf("Expected omit,value %+v,err %v", test.param, err)
+			}
+		}
+	}
+
+	validator := New()
+	validator.SetValidator("notrequired", func(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+		return false, fmt.Errorf("%v is invalid", params["name"])
+	})
+	validator.SetSkipOnStructEmpty(false)
+	testRequiredName := struct {
+		Name string `validate:"notrequired"`
+	}{}
+	if err := validator.Validate(testRequiredName); err != nil {
+		t.Errorf("Expected omit,err %v", err)
+	}
+}
+
+type comparePeriod struct {
+	BeginTime string `validate:"datetime"`
+	EndTime   string `validate:"datetime||gtfield=BeginTime"`
+}
+
+type compareOrder struct {
+	Password        string  `validate:"string=6,_"`
+	ConfirmPassword string  `validate:"eqfield=Password"`
+	OldPassword     string  `validate:"nefield=Password"`
+	MinPrice        float64 `validate:"float=0,_"`
+	MaxPrice        int64   `validate:"gtefield=MinPrice"`
+	Count           uint32  `validate:"ltefield=MaxPrice"`
+	Period          *comparePeriod
+	CloseTime       string    `validate:"gtfield=Period.EndTime"`
+	Deadline        time.Time `validate:"ltfield=Expire"`
+	Expire          time.Time
+}
+
+type compareBase struct {
+	Begin int64
+	Email string
+}
+
+//嵌入的 struct 指针为 nil 时，提升的字段视为零值
+type compareEmbed struct {
+	*compareBase
+	End       int64  `validate:"gtfield=Begin"`
+	Phone     string `validate:"required_without=Email"`
+	Period    *comparePeriod
+	CloseTime string `validate:"gtfield=Period.EndTime"`
+}
+
+func TestFieldCompare(t *testing.T) {
+	validator := New()
+	now := time.Now()
+	period := &comparePeriod{"2018-03-03 05:00:00", "2018-03-04 05:00:00"}
+	testCompare := []struct {
+		param    *compareOrder
+		expected int
+	}{
+		{&compareOrder{"123456", "123456", "654321", 9.5, 10, 10, period, "2018-03-05 00:00:00", now, now.Add(time.Hour)}, 0},
+		{&compareOrder{"123456", "12345", "123456", 9.5, 9, 10, period, "2018-03-05 00:00:00", now, now.Add(time.Hour)}, 4},
+		{&compareOrder{"123456", "123456", "654321", 9.5, 10, 1, &comparePeriod{"2018-03-03 05:00:00", "2018-03-03 05:00:00"}, "2018-03-03 05:00:00", now, now}, 3},
+	}
+	for _, test := range testCompare {
+		err := validator.Validate(test.param)
+		if len(err) != test.expected {
+			t.Errorf("Expected field compare,value %+v,err %v", test.param, err)
+		}
+	}
+
+	testType := struct {
+		Name string `validate:"gtfield=Age"`
+		Age  int64
+		Sex  string `validate:"eqfield=Gender"`
+	}{"张三", 1, "male"}
+	err := validator.Validate(testType)
+	if len(err) != 2 || err[0].Error() != "Name can not compare with Age" || err[1].Error() != "Sex validator field Gender not exist" {
+		t.Errorf("Expected field compare,err %v", err)
+	}
+
+	testEmbed := []struct {
+		param    *compareEmbed
+		expected []string
+	}{
+		{&compareEmbed{End: 1, Phone: "13800138000", CloseTime: "2018-03-05 00:00:00"}, nil},
+		{&compareEmbed{}, []string{"End", "Phone", "CloseTime"}},
+		{&compareEmbed{compareBase: &compareBase{Begin: 2, Email: "123456@qq.com"}, End: 1, CloseTime: "2018-03-05 00:00:00"}, []string{"End"}},
+	}
+	for _, test := range testEmbed {
+		assertPaths(t, validator.Validate(test.param), test.expected, "field compare embed", test.param)
+	}
+	embedValidator := New()
+	embedValidator.RegisterStructValidator(compareEmbed{}, func(sl *StructLevel, val reflect.Value) []error {
+		return []error{sl.ReportError("Begin", "begin", "[name] is invalid")}
+	})
+	assertPaths(t, embedValidator.Validate(&compareEmbed{End: 1, Phone: "1", CloseTime: "1"}), []string{"Begin"}, "field compare embed report", nil)
+}
+
+type invoiceOrder struct {
+	NeedInvoice  bool
+	InvoiceTitle string `validate:"required_if=NeedInvoice,true"`
+	Type         int64
+	Remark       *string `validate:"required_unless=Type,1"`
+	Email        string
+	Phone        string `validate:"required_without=Email"`
+	Address      string
+	ZipCode      string `validate:"required_with=Address,Phone"`
+}
+
+func TestRequiredCondition(t *testing.T) {
+	validator := New()
+	remark := "备注"
+	testCondition := []struct {
+		param    *invoiceOrder
+		expected []string
+	}{
+		{&invoiceOrder{Type: 1, Email: "123456@qq.com"}, nil},
+		{&invoiceOrder{NeedInvoice: true, InvoiceTitle: "公司", Remark: &remark, Phone: "13800138000", ZipCode: "100000"}, nil},
+		{&invoiceOrder{NeedInvoice: true, Type: 2}, []string{"InvoiceTitle", "Remark", "Phone"}},
+		{&invoiceOrder{Type: 1, Email: "123456@qq.com", Address: "北京"}, []string{"ZipCode"}},
+	}
+	for _, test := range testCondition {
+		var fields []string
+		for _, err := range validator.Validate(test.param) {
+			fields = append(fields, err.(*ValidationError).Field)
+		}
+		if !reflect.DeepEqual(fields, test.expected) {
+			t.Errorf("Expected required condition,value %+v,err %v", test.param, fields)
+		}
+	}
+
+	if err := validator.LazyValidate(&invoiceOrder{NeedInvoice: true, Type: 2}); err == nil || err.Error() != "InvoiceTitle is must required" {
+		t.Errorf("Expected required condition,err %v", err)
+	}
+	validator.SetValidator("required_if", &RequiredIfValidator{EMsg: "[name] 不能为空"})
+	if err := validator.LazyValidate(&invoiceOrder{NeedInvoice: true, Type: 2}); err == nil || err.Error() != "InvoiceTitle 不能为空" {
+		t.Errorf("Expected required condition,err %v", err)
+	}
+}
+
+type diveT struct {
+	Hobby  []string          `validate:"array=1,5||dive||string=1,10"`
+	Emails []*string         `validate:"dive||required||email"`
+	Scores map[string]int64  `validate:"dive||integer=0,100"`
+	Matrix [][]string        `validate:"array=_,3||dive||array=1,2||dive||in=a,b"`
+	Tags   []string          `validate:"dive||unique"`
+	Remark map[string]string `validate:"omitnil||dive||omitempty||string=_,3"`
+}
+
+func TestDive(t *testing.T) {
+	validator := New()
+	email, badEmail := "123456@qq.com", "qq.com"
+	testDive := []struct {
+		param    *diveT
+		expected []string
+	}{
+		{&diveT{
+			Hobby:  []string{"swimming", "跑步"},
+			Emails: []*string{&email},
+			Scores: map[string]int64{"math": 100},
+			Matrix: [][]string{{"a"}, {"a", "b"}},
+			Tags:   []string{"a", "b"},
+			Remark: map[string]string{"a": "", "b": "bbb"},
+		}, nil},
+		{&diveT{
+			Hobby:  []string{"swimming", "", "running running"},
+			Emails: []*string{&email, nil, &badEmail},
+			Scores: map[string]int64{"math": 101},
+			Matrix: [][]string{{"a", "c"}, {}},
+			Tags:   []string{"a", "b", "a"},
+			Remark: map[string]string{"a": "aaaa"},
+		}, []string{"Hobby[1]", "Hobby[2]", "Emails[1]", "Emails[2]", `Scores["math"]`, "Matrix[0][1]", "Matrix[1]", "Tags[2]", `Remark["a"]`}},
+		{&diveT{Hobby: []string{"swimming"}}, nil},
+	}
+	for _, test := range testDive {
+		var paths []string
+		for _, err := range validator.Validate(test.param) {
+			paths = append(paths, err.(*ValidationError).Path)
+		}
+		if !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("Expected dive,value %+v,err %v", test.param, paths)
+		}
+	}
+
+	testNotArray := struct {
+		Name string `validate:"dive||string=1,2"`
+	}{"abc"}
+	if err := validator.LazyValidate(testNotArray); err == nil || err.(*ValidationError).Rule != "dive" {
+		t.Errorf("Expected dive,err %v", err)
+	}
+}
+
+type keysLimit struct {
+	Max int64 `validate:"integer=1,_"`
+}
+
+func TestMapKeys(t *testing.T) {
+	validator := New()
+	validator.SetValidator("lower", &EmailValidator{Reg: `^[a-z_]+$`, EMsg: "[name] key should be lowercase"})
+	type keysT struct {
+		Limits map[string]keysLimit `validate:"dive||keys||lower||in=cpu,memory,disk_io||endkeys"`
+		Ranks  map[int64]string     `validate:"array=1,_||dive||keys||integer=1,10||endkeys||string=1,5"`
+	}
+	testMap := []struct {
+		param    *keysT
+		expected []string
+	}{
+		{&keysT{Limits: map[string]keysLimit{"cpu": {1}, "disk_io": {2}}, Ranks: map[int64]string{1: "张三"}}, nil},
+		{&keysT{Limits: map[string]keysLimit{"CPU": {1}}, Ranks: map[int64]string{11: "张三张三张三"}}, []string{`Limits["CPU"]`, `Limits["CPU"]`, "Ranks[11]", "Ranks[11]"}},
+		{&keysT{Limits: map[string]keysLimit{"gpu": {1}}, Ranks: map[int64]string{1: "张三"}}, []string{`Limits["gpu"]`}},
+		{&keysT{Limits: map[string]keysLimit{"cpu": {0}}, Ranks: map[int64]string{1: "张三"}}, []string{`Limits["cpu"].Max`}},
+	}
+	for _, test := range testMap {
+		var paths []string
+		for _, err := range validator.Validate(test.param) {
+			paths = append(paths, err.(*ValidationError).Path)
+		}
+		if !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("Expected keys,value %+v,err %v", test.param, paths)
+		}
+	}
+	err := validator.LazyValidate(&keysT{Limits: map[string]keysLimit{"CPU": {1}}})
+	if vErr, ok := err.(*ValidationError); !ok || vErr.Value != "CPU" || vErr.Msg != "Limits key should be lowercase" {
+		t.Errorf("Expected keys,err %v", err)
+	}
+
+	testInvalid := struct {
+		Hobby  []string          `validate:"dive||keys||string||endkeys"`
+		Limits map[string]string `validate:"keys||endkeys"`
+		Tags   map[string]string `validate:"dive||keys||string"`
+	}{[]string{"a"}, map[string]string{"a": "a"}, map[string]string{"a": "a"}}
+	if err := validator.Validate(testInvalid); len(err) != 4 {
+		t.Errorf("Expected keys,err %v", err)
+	}
+}
+
+type groupStudent struct {
+	Uid   int64  `validate:"create:integer=0||update:required||integer=0,1000000"`
+	Name  string `validate:"create:required||update:omitempty||string=1,5"`
+	Email string `validate:"create,update:omitempty||email"`
+}
+
+type groupFlow struct {
+	Hobby []string `validate:"update:dive||string=1,2"`
+}
+
+type groupFlowKeys struct {
+	Scores map[string]string `validate:"dive||update:keys||string=1,2||endkeys"`
+}
+
+type groupFlowEndKeys struct {
+	Scores map[string]string `validate:"dive||keys||string=1,2||update:endkeys"`
+}
+
+func TestGroups(t *testing.T) {
+	validator := New()
+	testGroups := []struct {
+		param    *groupStudent
+		groups   []string
+		expected int
+	}{
+		{&groupStudent{Name: "张三"}, []string{"create"}, 0},
+		{&groupStudent{Uid: 1, Name: "张三"}, []string{"create"}, 1},
+		{&groupStudent{}, []string{"create"}, 2},
+		{&groupStudent{Uid: 1}, []string{"update"}, 0},
+		{&groupStudent{Name: "张三张三张三", Email: "qq.com"}, []string{"update"}, 3},
+		{&groupStudent{Uid: 1, Email: "qq.com"}, nil, 2},
+		{&groupStudent{Uid: 1}, []string{"create", "update"}, 2},
+	}
+	for _, test := range testGroups {
+		err := validator.ValidateGroups(test.param, test.groups...)
+		if len(err) != test.expected {
+			t.Errorf("Expected groups,value %+v %v,err %v", test.param, test.groups, err)
+		}
+	}
+	if err := validator.LazyValidateGroups(&groupStudent{}, "update"); err == nil || err.Error() != "Uid is must required" {
+		t.Errorf("Expected groups,err %v", err)
+	}
+	//dive、keys、endkeys 不能带分组
+	testFlow := []struct {
+		param    interface{}
+		expected string
+	}{
+		{&groupFlow{Hobby: []string{"abc"}}, "validator dive can not have groups"},
+		{&groupFlowKeys{Scores: map[string]string{"a": "abc"}}, "validator keys can not have groups"},
+		{&groupFlowEndKeys{Scores: map[string]string{"a": "abc"}}, "validator endkeys can not have groups"},
+	}
+	for _, test := range testFlow {
+		for _, groups := range [][]string{nil, {"create"}, {"update"}} {
+			err := validator.ValidateGroups(test.param, groups...)
+			if len(err) != 1 || err[0].Error() != test.expected {
+				t.Errorf("Expected groups flow %+v %v,err %v", test.param, groups, err)
+			}
+		}
+	}
+}
+
+type partialClass struct {
+	Cid   int64  `validate:"required||integer=1,1000000"`
+	Cname string `validate:"required||string=1,5"`
+}
+
+type partialStudent struct {
+	Name     string         `validate:"required||string=1,5"`
+	Password string         `validate:"required||string=6,20"`
+	Class    []partialClass `validate:"array=1,3"`
+	Leader   partialClass
+}
+
+func TestPartial(t *testing.T) {
+	validator := New()
+	student := &partialStudent{
+		Name:   "张三张三张三",
+		Class:  []partialClass{{Cid: 2000000, Cname: "一班一班一班"}, {Cid: 1, Cname: "二班"}},
+		Leader: partialClass{Cid: 1, Cname: "一班一班一班"},
+	}
+	testPartial := []struct {
+		fields   []string
+		expected []string
+	}{
+		{[]string{"Name"}, []string{"Name"}},
+		{[]string{"Class.Cname"}, []string{"Class[0].Cname"}},
+		{[]string{"Class[1].Cname"}, []string{"Class[0].Cname"}},
+		{[]string{"Class"}, []string{"Class[0].Cid", "Class[0].Cname"}},
+		{[]string{"Name", "Leader.Cname"}, []string{"Name", "Leader.Cname"}},
+		{[]string{"Age"}, []string{"Age"}},
+		{[]string{"Name", "Class.Age", "Leader.Cname"}, []string{"Class.Age"}},
+	}
+	for _, test := range testPartial {
+		err := validator.ValidatePartial(student, test.fields...)
+		assertPaths(t, err, test.expected, "partial", test.fields)
+	}
+	testExcept := []struct {
+		fields   []string
+		expected []string
+	}{
+		{[]string{"Password"}, []string{"Name", "Class[0].Cid", "Class[0].Cname", "Leader.Cname"}},
+		{[]string{"Password", "Class", "Leader.Cname"}, []string{"Name"}},
+		{[]string{"Name", "Password", "Class[0].Cid", "Leader"}, []string{"Class[0].Cname"}},
+	}
+	for _, test := range testExcept {
+		err := validator.ValidateExcept(student, test.fields...)
+		assertPaths(t, err, test.expected, "except", test.fields)
+	}
+	if err := validator.LazyValidatePartial(student, "Class.Cid"); err == nil || err.(*ValidationError).Path != "Class[0].Cid" {
+		t.Errorf("Expected lazy partial,err %v", err)
+	}
+	if err := validator.LazyValidateExcept(student, "Name"); err == nil || err.(*ValidationError).Path != "Password" {
+		t.Errorf("Expected lazy except,err %v", err)
+	}
+	if err := validator.LazyValidatePartial(student, "Age"); err == nil || err.(*ValidationError).Rule != VALIDATOR_PARTIAL {
+		t.Errorf("Expected lazy partial not exist,err %v", err)
+	}
+	if err := validator.ValidateExcept(student, "Password", "Age"); len(err) != 1 || err[0].(*ValidationError).Rule != VALIDATOR_EXCEPT {
+		t.Errorf("Expected except not exist,err %v", err)
+	}
+	//SetFieldNameFunc 生成的字段名和 struct 字段名都可以使用
+	lowerValidator := New().SetFieldNameFunc(func(field reflect.StructField) string {
+		return strings.ToLower(field.Name)
+	})
+	testNamePartial := []struct {
+		fields   []string
+		expected []string
+	}{
+		{[]string{"name"}, []string{"name"}},
+		{[]string{"class.cname"}, []string{"class[0].cname"}},
+		{[]string{"Leader.Cname"}, []string{"leader.cname"}},
+		{[]string{"leader.Cname", "Class.cid"}, []string{"class[0].cid", "leader.cname"}},
+	}
+	for _, test := range testNamePartial {
+		err := lowerValidator.ValidatePartial(student, test.fields...)
+		assertPaths(t, err, test.expected, "name partial", test.fields)
+	}
+	assertPaths(t, lowerValidator.ValidateExcept(student, "password", "class", "Leader"), []string{"name"}, "name except", student)
+}
+
+func TestValidateVar(t *testing.T) {
+	validator := New()
+	email := "qq.com"
+	testVar := []struct {
+		value    interface{}
+		rules    string
+		expected int
+	}{
+		{"test@qq.com", "required||email", 0},
+		{"qq.com", "required||email", 1},
+		{"", "required||email", 2},
+		{"", "email", 1},
+		{nil, "required", 1},
+		{nil, "email", 0},
+		{(*string)(nil), "required||email", 1},
+		{&email, "email", 1},
+		{int64(5), "integer=1,10", 0},
+		{int64(50), "integer=1,10", 1},
+		{[]string{"a", "b", "a"}, "array=1,5||unique", 1},
+		{[]string{"a", "bbbbbb"}, "dive||string=1,5", 1},
+		{"abc", "eqfield=Name", 1},
+		{"abc", "notexist", 1},
+		{"abc", "", 0},
+	}
+	for _, test := range testVar {
+		err := validator.ValidateVar(test.value, test.rules)
+		if len(err) != test.expected {
+			t.Errorf("Expected var %v %v,err %v", test.value, test.rules, err)
+		}
+	}
+	err := validator.ValidateVarWithName("qq.com", "email", "email")
+	if len(err) != 1 || err[0].Error() != "email is not a email address" || err[0].(*ValidationError).Path != "email" {
+		t.Errorf("Expected var with name,err %v", err)
+	}
+	err = validator.ValidateVar([]string{"a", "bbbbbb"}, "dive||string=1,5")
+	assertPaths(t, err, []string{"value[1]"}, "var dive", "dive||string=1,5")
+	//没有同级字段，引用字段的验证器返回字段不存在
+	testField := []struct {
+		value    interface{}
+		rules    string
+		expected string
+	}{
+		{"abc", "eqfield=Name", "value validator field Name not exist"},
+		{"", "required_without=Email", "value validator field Email not exist"},
+		{"", "required_if=Type,1", "value validator field Type not exist"},
+	}
+	for _, test := range testField {
+		err := validator.ValidateVar(test.value, test.rules)
+		if len(err) != 1 || err[0].Error() != test.expected || !errors.Is(err[0], ErrValidatorConfig) {
+			t.Errorf("Expected var field %v %v,err %v", test.value, test.rules, err)
+		}
+	}
+}
+
+func TestValidateMap(t *testing.T) {
+	validator := New()
+	rules := map[string]interface{}{
+		"name":       "required||string=1,5",
+		"email":      "email",
+		"password":   "required||string=6,20",
+		"repassword": "required||eqfield=password",
+		"tags":       "dive||string=1,5",
+		"class": map[string]interface{}{
+			"cid":   "required||integer=1,1000000",
+			"cname": "required||string=1,5",
+		},
+	}
+	//数据通过 json.Unmarshal 得到，数字为 float64
+	testMap := []struct {
+		data     string
+		expected []string
+	}{
+		{`{"name":"张三","password":"123456","repassword":"123456","tags":["a","b"],"class":{"cid":1,"cname":"一班"}}`, nil},
+		{`{"password":"123456","repassword":"1234567"}`, []string{"class.cid", "class.cname", "name", "repassword"}},
+		{`{"name":"张三张三张三","email":"qq.com","password":"123456","repassword":"123456","tags":["a","bbbbbb"],"class":[{"cid":1,"cname":"一班"},{"cname":"二班"}]}`, []string{"class[1].cid", "email", "name", "tags[1]"}},
+		{`{"name":"张三","password":"123456","repassword":"123456","class":"一班"}`, []string{"class"}},
+		{`{"name":"张三","password":"123456","repassword":"123456","class":[{"cid":1.5,"cname":"一班"},{"cid":2000000,"cname":"二班"}]}`, []string{"class[0].cid", "class[1].cid"}},
+	}
+	for _, test := range testMap {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(test.data), &data); err != nil {
+			t.Fatalf("Expected map json %v,err %v", test.data, err)
+		}
+		err := validator.ValidateMap(data, rules)
+		assertPaths(t, err, test.expected, "map", test.data)
+	}
+	//嵌套的值本身的规则
+	testSelf := []struct {
+		rule     string
+		data     map[string]interface{}
+		expected []string
+	}{
+		{"required", map[string]interface{}{}, []string{"class"}},
+		{"required", map[string]interface{}{"class": map[string]interface{}{"cname": "一班"}}, []string{"class.cid"}},
+		{"omitempty", map[string]interface{}{}, nil},
+		{"omitempty", map[string]interface{}{"class": map[string]interface{}{}}, nil},
+		{"omitnil", map[string]interface{}{"class": nil}, nil},
+		{"array=1,_", map[string]interface{}{"class": []interface{}{map[string]interface{}{"cname": "一班"}}}, []string{"class[0].cid"}},
+		{"array=_,1", map[string]interface{}{"class": []interface{}{map[string]interface{}{}, map[string]interface{}{}}}, []string{"class"}},
+	}
+	for _, test := range testSelf {
+		selfRules := map[string]interface{}{
+			"class": map[string]interface{}{
+				"":      test.rule,
+				"cid":   "required||integer=1,1000000",
+				"cname": "string=1,5",
+			},
+		}
+		assertPaths(t, validator.ValidateMap(test.data, selfRules), test.expected, "map self "+test.rule, test.data)
+	}
+	//嵌套的值不存在时，同级字段视为零值
+	compareRules := map[string]interface{}{"class": map[string]interface{}{"cname": "eqfield=alias", "cid": "required_with=cname"}}
+	assertPaths(t, validator.ValidateMap(map[string]interface{}{}, compareRules), nil, "map compare", compareRules)
+	err := validator.ValidateMap(map[string]interface{}{"cid": 1.5}, map[string]interface{}{"cid": "integer"})
+	if len(err) != 1 || err[0].Error() != "cid is not a integer" {
+		t.Errorf("Expected map float integer,err %v", err)
+	}
+}
+
+type levelGuardian struct {
+	Name string `validate:"required"`
+}
+
+type levelStudent struct {
+	Name     string         `validate:"required||string=1,5" json:"name"`
+	Age      int64          `validate:"integer=1,100" json:"age"`
+	Email    string         `json:"email"`
+	Phone    string         `json:"phone"`
+	Guardian *levelGuardian `title:"监护人" json:"guardian"`
+}
+
+type levelClass struct {
+	Students []levelStudent
+	Monitors map[string]levelStudent
+}
+
+func TestStructValidator(t *testing.T) {
+	validator := New()
+	err := validator.RegisterStructValidator(levelStudent{}, func(sl *StructLevel, val reflect.Value) []error {
+		var errs []error
+		student := val.Interface().(levelStudent)
+		if student.Email == "" && student.Phone == "" {
+			errs = append(errs, fmt.Errorf("email or phone is required"))
+		}
+		if student.Age < 18 && student.Guardian == nil {
+			errs = append(errs, sl.ReportError("Guardian", "guardian", "[name] is must required"))
+		}
+		if student.Guardian != nil && student.Guardian.Name == student.Name {
+			errs = append(errs, sl.ReportError("Guardian.Name", "nefield", "[name] should not be equal Name"))
+		}
+		return errs
+	})
+	if err != nil {
+		t.Fatalf("Expected register struct validator,err %v", err)
+	}
+	if err := validator.RegisterStructValidator("student", nil); err == nil {
+		t.Errorf("Expected register struct validator error")
+	}
+	testStruct := []struct {
+		param    interface{}
+		expected []string
+	}{
+		{&levelStudent{Name: "张三", Age: 20, Email: "test@qq.com"}, nil},
+		{&levelStudent{Name: "张三", Age: 20}, []string{""}},
+		{&levelStudent{Name: "张三", Age: 10, Phone: "123"}, []string{"Guardian"}},
+		{&levelStudent{Name: "张三", Age: 10, Phone: "123", Guardian: &levelGuardian{Name: "张三"}}, []string{"Guardian.Name"}},
+		{&levelClass{Students: []levelStudent{{Name: "张三", Age: 20, Phone: "123"}, {Name: "李四", Age: 10}}}, []string{"Students[1]", "Students[1].Guardian"}},
+		{&levelClass{Monitors: map[string]levelStudent{"math": {Name: "张三张三张三", Age: 10, Phone: "123"}}}, []string{`Monitors["math"].Name`, `Monitors["math"].Guardian`}},
+	}
+	for _, test := range testStruct {
+		err := validator.Validate(test.param)
+		assertPaths(t, err, test.expected, "struct validator", test.param)
+	}
+	err = validator.LazyValidate(&levelStudent{Name: "张三", Age: 10, Phone: "123"})
+	if vErr, ok := err.(*ValidationError); !ok || vErr.Field != "监护人" || vErr.Rule != "guardian" || vErr.Msg != "监护人 is must required" {
+		t.Errorf("Expected struct validator error,err %v", err)
+	}
+	if err := validator.ValidatePartial(&levelStudent{Name: "张三", Age: 20}, "Name"); len(err) != 0 {
+		t.Errorf("Expected struct validator partial,err %v", err)
+	}
+	validator.SetFieldNameFunc(JsonFieldName)
+	err = validator.LazyValidate(&levelStudent{Name: "张三", Age: 10, Phone: "123", Guardian: &levelGuardian{Name: "张三"}})
+	if vErr, ok := err.(*ValidationError); !ok || vErr.Path != "guardian.Name" {
+		t.Errorf("Expected struct validator json path,err %v", err)
+	}
+}
+
+type selfMoney int64
+
+func (self selfMoney) ValidateSelf() error {
+	if self < 0 {
+		return fmt.Errorf("money should not be negative")
+	}
+	return nil
+}
+
+type selfDateRange struct {
+	Begin string `validate:"required||datetime=Y-m-d"`
+	End   string `validate:"required||datetime=Y-m-d"`
+}
+
+func (self *selfDateRange) ValidateSelf() error {
+	if self.Begin > self.End {
+		return fmt.Errorf("begin should be before end")
+	}
+	return nil
+}
+
+var selfValidator = New()
+
+//Validate 封装了对自身的验证，不会被自动调用
+type selfRequest struct {
+	Name string `validate:"required||string=1,5"`
+}
+
+func (self *selfRequest) Validate() error {
+	return selfValidator.LazyValidate(self)
+}
+
+type selfOrder struct {
+	Price  selfMoney
+	Items  []selfMoney
+	Period selfDateRange
+	Ranges map[string]selfDateRange
+}
+
+func TestValidatable(t *testing.T) {
+	validator := New()
+	testSelf := []struct {
+		param    interface{}
+		expected []string
+	}{
+		{&selfOrder{Price: 1, Items: []selfMoney{1, 2}, Period: selfDateRange{"2020-01-01", "2020-02-01"}}, nil},
+		{&selfOrder{Price: -1, Items: []selfMoney{1, -2}, Period: selfDateRange{"2020-01-01", "2020-02-01"}}, []string{"Price", "Items[1]"}},
+		{&selfOrder{Period: selfDateRange{"2020-03-01", "2020-02-01"}}, []string{"Period"}},
+		{&selfOrder{Period: selfDateRange{"2020-03-01", "2020-02"}, Ranges: map[string]selfDateRange{"a": {"2020-03-01", "2020-02-01"}}}, []string{"Period.End", "Period", `Ranges["a"]`}},
+		{selfMoney(-1), []string{""}},
+		{&selfDateRange{"2020-03-01", "2020-02-01"}, []string{""}},
+	}
+	for _, test := range testSelf {
+		err := validator.Validate(test.param)
+		assertPaths(t, err, test.expected, "validatable", test.param)
+	}
+	err := validator.LazyValidate(&selfOrder{Price: -1})
+	if vErr, ok := err.(*ValidationError); !ok || vErr.Rule != VALIDATOR_VALIDATABLE || vErr.Msg != "money should not be negative" {
+		t.Errorf("Expected validatable error,err %v", err)
+	}
+	request := &selfRequest{Name: "张三张三张三"}
+	if err := request.Validate(); err == nil || err.(*ValidationError).Path != "Name" {
+		t.Errorf("Expected validate wrapper,err %v", err)
+	}
+	order := &selfOrder{Price: -1, Period: selfDateRange{"2020-03-01", "2020-02-01"}}
+	assertPaths(t, New().SetCallValidatable(false).Validate(order), nil, "call validatable", order)
+}
+
+type ctxTenantKey struct{}
+
+type ctxCancelKey struct{}
+
+type ctxTenantValidator struct {
+	EMsg string
+}
+
+func (self *ctxTenantValidator) Validate(ctx context.Context, params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+	tenant, _ := ctx.Value(ctxTenantKey{}).(string)
+	if val.String() != tenant {
+		return false, fmt.Errorf("%v should belong to tenant %v", params["name"], tenant)
+	}
+	return true, nil
+}
+
+type ctxStudent struct {
+	Tenant string   `validate:"tenant"`
+	Name   string   `validate:"required||string=1,5"`
+	Hobby  []string `validate:"dive||cancel"`
+}
+
+func TestValidateCtx(t *testing.T) {
+	validator := New()
+	validator.SetValidator("tenant", &ctxTenantValidator{})
+	validator.SetValidator("cancel", ValidatorCtxF(func(ctx context.Context, params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+		if cancel, ok := ctx.Value(ctxCancelKey{}).(context.CancelFunc); ok {
+			cancel()
+		}
+		return false, fmt.Errorf("%v is invalid", params["name"])
+	}))
+	ctx := context.WithValue(context.Background(), ctxTenantKey{}, "a")
+	if err := validator.ValidateCtx(ctx, &ctxStudent{Tenant: "a", Name: "张三"}); len(err) != 0 {
+		t.Errorf("Expected ctx,err %v", err)
+	}
+	if err := validator.ValidateCtx(ctx, &ctxStudent{Tenant: "b", Name: "张三"}); len(err) != 1 || err[0].Error() != "Tenant should belong to tenant a" {
+		t.Errorf("Expected ctx tenant,err %v", err)
+	}
+	if err := validator.Validate(&ctxStudent{Tenant: "b", Name: "张三"}); len(err) != 1 || err[0].Error() != "Tenant should belong to tenant " {
+		t.Errorf("Expected ctx background,err %v", err)
+	}
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := validator.ValidateCtx(canceledCtx, &ctxStudent{Tenant: "b"}); len(err) != 1 || !errors.Is(err[0], context.Canceled) || err[0].(*ValidationError).Rule != VALIDATOR_CTX {
+		t.Errorf("Expected ctx canceled,err %v", err)
+	}
+	if err := validator.LazyValidateCtx(canceledCtx, &ctxStudent{Tenant: "b"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected lazy ctx canceled,err %v", err)
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancelCtx = context.WithValue(cancelCtx, ctxCancelKey{}, cancel)
+	err := validator.ValidateCtx(cancelCtx, &ctxStudent{Tenant: "a", Name: "张三", Hobby: []string{"a", "b", "c"}})
+	if len(err) != 2 || err[0].(*ValidationError).Path != "Hobby[0]" || !errors.Is(err[1], context.Canceled) {
+		t.Errorf("Expected ctx cancel while validating,err %v", err)
+	}
+}
+
+type embedBase struct {
+	Id   int64  `validate:"required" json:"id"`
+	Name string `validate:"string=1,5" json:"name"`
+}
+
+type embedStudent struct {
+	embedBase
+	Age     int64          `validate:"integer=1,100" json:"age"`
+	classes []partialClass `validate:"array=_,3"`
+	leader  partialClass
+}
+
+type embedTagStudent struct {
+	*embedBase `json:"base"`
+}
+
+type embedRequiredStudent struct {
+	embedBase `validate:"required"`
+	leader    partialClass `validate:"required"`
+	Birthday  time.Time    `validate:"required"`
+}
+
+func TestEmbedded(t *testing.T) {
+	student := &embedStudent{
+		embedBase: embedBase{Name: "张三张三张三"},
+		Age:       200,
+		classes:   []partialClass{{Cid: 1, Cname: "一班一班一班"}},
+		leader:    partialClass{Cid: 2000000, Cname: "一班"},
+	}
+	testEmbedded := []struct {
+		validator *goValidator
+		param     interface{}
+		expected  []string
+	}{
+		{New(), student, []string{"Id", "Name", "Age"}},
+		{New().SetFlattenEmbedded(false), student, []string{"Age"}},
+		{New().SetFlattenEmbedded(false).SetValidateUnexported(true), student, []string{"embedBase.Id", "embedBase.Name", "Age", "classes[0].Cname", "leader.Cid"}},
+		{New().SetValidateUnexported(true), student, []string{"Id", "Name", "Age", "classes[0].Cname", "leader.Cid"}},
+		{New().SetFieldNameFunc(JsonFieldName), student, []string{"id", "name", "age"}},
+		{New().SetFieldNameFunc(JsonFieldName), &embedTagStudent{&embedBase{Name: "张三张三张三"}}, nil},
+		{New().SetFieldNameFunc(JsonFieldName).SetValidateUnexported(true), &embedTagStudent{&embedBase{Name: "张三张三张三"}}, []string{"base.id", "base.name"}},
+		{New(), &embedTagStudent{&embedBase{Name: "张三张三张三"}}, nil},
+		{New().SetValidateUnexported(true), &embedTagStudent{&embedBase{Name: "张三张三张三"}}, []string{"embedBase.Id", "embedBase.Name"}},
+		{New().SetFieldNameFunc(TagFieldName("title")), &embedTagStudent{&embedBase{Name: "张三张三张三"}}, nil},
+		{New().SetFieldNameFunc(TagFieldName("title")), student, []string{"Id", "Name", "Age"}},
+		{New(), &embedRequiredStudent{}, []string{"embedBase", "leader", "Birthday"}},
+		{New(), &embedRequiredStudent{embedBase: embedBase{Id: 1, Name: "张三"}, leader: partialClass{Cid: 1}, Birthday: time.Now()}, nil},
+	}
+	for _, test := range testEmbedded {
+		err := test.validator.Validate(test.param)
+		assertPaths(t, err, test.expected, "embedded", test.param)
+	}
+	assertPaths(t, New().ValidatePartial(student, "Name"), []string{"Name"}, "embedded partial", student)
+}
+
+type ifacePayment interface {
+	Pay() string
+}
+
+type ifaceCard struct {
+	Number string `validate:"required||string=16"`
+}
+
+func (self ifaceCard) Pay() string {
+	return self.Number
+}
+
+type ifaceWallet struct {
+	Account string `validate:"required||email"`
+}
+
+func (self *ifaceWallet) Pay() string {
+	return self.Account
+}
+
+type ifaceOrder struct {
+	Payment  ifacePayment `validate:"required"`
+	Backups  []ifacePayment
+	Extra    interface{} `validate:"string=1,5"`
+	Metadata map[string]interface{}
+}
+
+func TestInterfaceField(t *testing.T) {
+	validator := New()
+	testIface := []struct {
+		param    interface{}
+		expected []string
+	}{
+		{&ifaceOrder{Payment: ifaceCard{"1234567812345678"}}, nil},
+		{&ifaceOrder{}, []string{"Payment"}},
+		{&ifaceOrder{Payment: (*ifaceWallet)(nil)}, []string{"Payment"}},
+		{&ifaceOrder{Payment: ifaceCard{"1234"}}, []string{"Payment.Number"}},
+		{&ifaceOrder{Payment: &ifaceWallet{"qq.com"}, Extra: "abcdef"}, []string{"Payment.Account", "Extra"}},
+		{&ifaceOrder{Payment: ifaceCard{"1234567812345678"}, Extra: 1}, []string{"Extra"}},
+		{&ifaceOrder{Payment: ifaceCard{"1234567812345678"}, Backups: []ifacePayment{ifaceCard{"1234"}, nil, &ifaceWallet{"qq.com"}}}, []string{"Backups[0].Number", "Backups[2].Account"}},
+		{&ifaceOrder{Payment: ifaceCard{"1234567812345678"}, Metadata: map[string]interface{}{"card": ifaceCard{"1234"}, "name": "a"}}, []string{`Metadata["card"].Number`}},
+		{[]interface{}{ifaceCard{"1234"}, "a"}, []string{"[0].Number"}},
+	}
+	for _, test := range testIface {
+		err := validator.Validate(test.param)
+		assertPaths(t, err, test.expected, "interface", test.param)
+	}
+	//interface 中的字段无法通过类型判断是否存在
+	order := &ifaceOrder{Payment: &ifaceWallet{"qq.com"}, Extra: "abcdef"}
+	assertPaths(t, validator.ValidatePartial(order, "Payment.Account"), []string{"Payment.Account"}, "interface partial", order)
+	if err := validator.ValidatePartial(order, "Payment.Number"); len(err) != 0 {
+		t.Errorf("Expected interface partial,err %v", err)
+	}
+}
+
+type treeNode struct {
+	Name     string      `validate:"required||string=1,5"`
+	Parent   *treeNode   `json:"parent"`
+	Children []*treeNode `json:"children"`
+	Links    map[string]*treeNode
+}
+
+func TestPointerCycle(t *testing.T) {
+	root := &treeNode{Name: "root"}
+	child := &treeNode{Name: "child", Parent: root}
+	grandson := &treeNode{Name: "grandson", Parent: child}
+	child.Children = []*treeNode{grandson, nil}
+	root.Children = []*treeNode{child, child}
+	root.Links = map[string]*treeNode{"self": root, "grandson": grandson}
+	grandson.Links = map[string]*treeNode{"root": root}
+
+	validator := New()
+	//只跳过当前递归路径上的指针，共享的指针在每个位置都会验证
+	assertPaths(t, validator.Validate(root), []string{"Children[0].Children[0].Name", "Children[1].Children[0].Name", `Links["grandson"].Name`}, "pointer cycle", root)
+	grandson.Name = "孙子"
+	if err := validator.Validate(root); len(err) != 0 {
+		t.Errorf("Expected pointer cycle,err %v", err)
+	}
+	nodes := []*treeNode{{Name: "a"}, {Name: "abcdef"}}
+	assertPaths(t, validator.Validate(nodes), []string{"[1].Name"}, "pointer elements", nodes)
+
+	validator.SetMaxDepth(2)
+	err := validator.Validate(root)
+	if len(err) != 1 || err[0].(*ValidationError).Rule != VALIDATOR_MAX_DEPTH || err[0].Error() != "Children[0].Children[0] exceeds max depth 2" {
+		t.Errorf("Expected max depth,err %v", err)
+	}
+	if err := validator.LazyValidate(&treeNode{Name: "a", Children: []*treeNode{{Name: "b"}}}); err != nil {
+		t.Errorf("Expected max depth,err %v", err)
+	}
+	validator.SetMaxDepth(0)
+	if err := validator.Validate(root); len(err) != 0 {
+		t.Errorf("Expected no max depth,err %v", err)
+	}
+
+	//slice 引用自身
+	cycle := []interface{}{nil, &treeNode{Name: "abcdef"}}
+	cycle[0] = cycle
+	assertPaths(t, New().SetMaxDepth(5).Validate(cycle), []string{"[1].Name"}, "slice cycle", nil)
+	assertPaths(t, New().Validate(cycle), []string{"[1].Name"}, "slice cycle", nil)
+	//slice、map、interface 的每一层都计入嵌套层数
+	var nested interface{} = &treeNode{Name: "a"}
+	for i := 0; i < 5; i++ {
+		nested = []interface{}{map[string]interface{}{"a": nested}}
+	}
+	assertPaths(t, New().SetMaxDepth(5).Validate(nested), []string{`[0]["a"][0]["a"][0]`}, "nested max depth", nil)
+	if err := New().SetMaxDepth(11).Validate(nested); len(err) != 0 {
+		t.Errorf("Expected nested max depth,err %v", err)
+	}
+
+	//验证过程中修改配置，使用 -race 检查
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			validator.SetMaxDepth(i % 3)
+			validator.SetCallValidatable(i%2 == 0)
+			validator.SetSkipOnStructEmpty(i%2 == 0)
+		}
+		done <- true
+	}()
+	for i := 0; i < 100; i++ {
+		validator.Validate(root)
+	}
+	<-done
+}
+
+type logicUser struct {
+	Contact  string `validate:"required||email|mobile"`
+	Username string `validate:"string=1,10||!in=admin,root"`
+	Age      int64  `validate:"(integer=1,17||!in=3)|integer=60,100"`
+	Nick     string `validate:"update:!(string=1,2|in=abc)"`
+}
+
+type logicField struct {
+	Aa string `validate:"!eqfield=Bb"`
+}
+
+type logicCompare struct {
+	Aa string
+	Bb string `validate:"!eqfield=Aa"`
+}
+
+func TestLogicRules(t *testing.T) {
+	validator := New()
+	validator.SetValidator("mobile", func(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+		if len(val.String()) != 11 {
+			return false, fmt.Errorf("%v is not a mobile", params["name"])
+		}
+		return true, nil
+	})
+	testLogic := []struct {
+		param    *logicUser
+		groups   []string
+		expected []string
+	}{
+		{&logicUser{Contact: "test@qq.com", Username: "zhangsan", Age: 10}, nil, nil},
+		{&logicUser{Contact: "13800138000", Username: "zhangsan", Age: 80}, nil, nil},
+		{&logicUser{Contact: "qq.com", Username: "admin", Age: 3}, nil, []string{
+			"Contact is not a email address or Contact is not a mobile",
+			"Username should not match in=admin,root",
+			"Age should not match in=3 or Age should be betwween 60 and 100",
+		}},
+		{&logicUser{Contact: "test@qq.com", Username: "zhangsan", Age: 30}, nil, []string{"Age should be betwween 1 and 17 or Age should be betwween 60 and 100"}},
+		{&logicUser{Contact: "test@qq.com", Username: "zhangsan", Age: 10, Nick: "ab"}, []string{"update"}, []string{"Nick should not match (string=1,2|in=abc)"}},
+		{&logicUser{Contact: "test@qq.com", Username: "zhangsan", Age: 10, Nick: "abcd"}, []string{"update"}, nil},
+	}
+	for _, test := range testLogic {
+		err := validator.ValidateGroups(test.param, test.groups...)
+		if len(err) != len(test.expected) {
+			t.Errorf("Expected logic %+v,err %v", test.param, err)
+			continue
+		}
+		for i, e := range err {
+			if e.Error() != test.expected[i] {
+				t.Errorf("Expected logic %+v,msg %v,err %v", test.param, test.expected[i], e)
+			}
+		}
+	}
+	if err := validator.LazyValidate(&logicUser{Contact: "qq.com"}); err.(*ValidationError).Rule != "email|mobile" {
+		t.Errorf("Expected logic rule,err %v", err)
+	}
+
+	testTag := []struct {
+		tag      string
+		expected string
+	}{
+		{"email|(mobile", "validator tag email|(mobile error at 13: missing \")\""},
+		{"email)", "validator tag email) error at 0: unexpected \"email)\" in email)"},
+		{"email|notexist", "validator notexist not exist"},
+		{"!omitempty", "validator omitempty can not be combined"},
+	}
+	for _, test := range testTag {
+		err := validator.ValidateVar("a", test.tag)
+		if len(err) != 1 || err[0].Error() != test.expected {
+			t.Errorf("Expected logic tag %v,err %v", test.tag, err)
+		}
+	}
+
+	//非运算、或运算不会把配置错误当作验证结果
+	testConfig := []struct {
+		param    interface{}
+		tag      string
+		expected string
+	}{
+		{"abc", "!string=a,b", "value validator range error"},
+		{struct{ A int }{1}, "!in=admin", "value type invalid"},
+		{"abc", "string=a,b|email", "value validator range error"},
+		{"abc", "!(email|string=a,b)", "value validator range error"},
+		{"root", "!in=admin", ""},
+		{"test@qq.com", "email|string=a,b", ""},
+	}
+	for _, test := range testConfig {
+		err := validator.ValidateVar(test.param, test.tag)
+		if test.expected == "" && len(err) != 0 || test.expected != "" && (len(err) != 1 || err[0].Error() != test.expected || !errors.Is(err[0], ErrValidatorConfig)) {
+			t.Errorf("Expected logic config %v %v,err %v", test.param, test.tag, err)
+		}
+	}
+	err := validator.Validate(&logicField{Aa: "a"})
+	if len(err) != 1 || err[0].Error() != "Aa validator field Bb not exist" || !errors.Is(err[0], ErrValidatorConfig) {
+		t.Errorf("Expected logic field config,err %v", err)
+	}
+	if err := validator.Validate(&logicCompare{Aa: "a", Bb: "b"}); len(err) != 0 {
+		t.Errorf("Expected logic field,err %v", err)
+	}
+	err = validator.Validate(&logicCompare{Aa: "a", Bb: "a"})
+	if len(err) != 1 || err[0].Error() != "Bb should not match eqfield=Aa" || errors.Is(err[0], ErrValidatorConfig) {
+		t.Errorf("Expected logic field,err %v", err)
+	}
+}
+
+func TestQuotedArgs(t *testing.T) {
+	validator := New()
+	validator.SetValidator("reg", func(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+		if len(args) != 1 {
+			return false, fmt.Errorf("%v reg args error %q", params["name"], args)
+		}
+		reg, err := compileRegexp(args[0])
+		if err != nil {
+			return false, err
+		}
+		if !reg.MatchString(val.String()) {
+			return false, fmt.Errorf("%v not match %v", params["name"], args[0])
+		}
+		return true, nil
+	})
+	testQuoted := []struct {
+		value    string
+		tag      string
+		expected string
+	}{
+		{"a,b", "in='a,b','c'", ""},
+		{"c", "in='a,b','c'", ""},
+		{"a", "in='a,b','c'", "value is not in params [a,b c]"},
+		{"x||y", `in="x||y",z`, ""},
+		{"it's", `in='it\'s',"say \"hi\""`, ""},
+		{`say "hi"`, `in='it\'s',"say \"hi\""`, ""},
+		{"a,b", `in=a\,b,c`, ""},
+		{"a|b", `in=a\|b`, ""},
+		{"a|b", "in=a|b", ""},
+		{"b", "in=a|b", "value is not in params [a|b]"},
+		{"b", "reg=^(a|b)$", ""},
+		{"c", "reg=^(a|b)$", "value not match ^(a|b)$"},
+		{"abc@qq.com", "in=a|email", ""},
+		{"c", "in=a|!in=b", ""},
+		{"it's", "in=it's,a", ""},
+		{"a=b,c", "reg='^a=b,c$'", ""},
+		{"x||y", "reg='^x\\|\\|y$'", ""},
+		{"123", `reg=^\d+$`, ""},
+		{"abc", `reg=^\d+$`, `value not match ^\d+$`},
+		{"abc", `!in='abc','d,e'`, "value should not match in=abc,'d,e'"},
+		{"a", "in='a,b", "validator tag in='a,b error at 3: unterminated quoted argument"},
+		{"a", "in='a'b", "validator tag in='a'b error at 6: unexpected \"b\" after quoted argument"},
+		{"a", "'in'=a", "validator tag 'in'=a error at 0: unexpected \"'in'\" in 'in'"},
+	}
+	for _, test := range testQuoted {
+		err := validator.ValidateVar(test.value, test.tag)
+		if test.expected == "" && len(err) != 0 || test.expected != "" && (len(err) != 1 || err[0].Error() != test.expected) {
+			t.Errorf("Expected quoted %v %v,err %v", test.value, test.tag, err)
+		}
+	}
+}
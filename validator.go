@@ -1,8 +1,12 @@
 package govalidators
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -12,6 +16,33 @@ const (
 	VALIDATOR_VALUE_SIGN  = "="
 	VALIDATOR_RANGE_SPLIT = ","
 	VALIDATOR_IGNORE_SIGN = "_"
+	//验证器分组分隔符，如 create:required
+	VALIDATOR_GROUP_SIGN  = ":"
+	VALIDATOR_GROUP_SPLIT = ","
+	//值为零值时，跳过后边的验证器
+	VALIDATOR_OMIT_EMPTY = "omitempty"
+	//值为 nil(指针、map、slice、interface)时，跳过后边的验证器
+	VALIDATOR_OMIT_NIL = "omitnil"
+	//后边的验证器对 slice、array、map 的每个元素执行
+	VALIDATOR_DIVE = "dive"
+	//dive 后边的 keys 和 endkeys 之间的验证器对 map 的 key 执行
+	VALIDATOR_KEYS     = "keys"
+	VALIDATOR_END_KEYS = "endkeys"
+	//ValidateVar 未指定名称时，错误提示中使用的名称
+	VALIDATOR_VAR_NAME = "value"
+	//struct 级别验证器返回的错误中的 Rule
+	VALIDATOR_STRUCT = "struct"
+	//实现 Validatable 接口的类型返回的错误中的 Rule
+	VALIDATOR_VALIDATABLE = "validatable"
+	//超过最大嵌套层数的错误中的 Rule
+	VALIDATOR_MAX_DEPTH = "max_depth"
+	//context 取消后返回的错误中的 Rule，可以通过 errors.Is 判断 context 的错误
+	VALIDATOR_CTX = "ctx"
+	//ValidateMap 中，嵌套 rules 里验证值本身的规则的 key，如 {"": "required", "cid": "integer"}
+	VALIDATOR_MAP_SELF = ""
+	//ValidatePartial、ValidateExcept 指定的字段路径不存在时返回的错误中的 Rule
+	VALIDATOR_PARTIAL = "partial"
+	VALIDATOR_EXCEPT  = "except"
 
 	//邮箱验证正则
 	MAIL_REG = `\A[\w+\-.]+@[a-z\d\-]+(\.[a-z]+)*\.[a-z]+\z`
@@ -20,7 +51,7 @@ const (
 	//是否为整数正则
 	INTEGER_REG = `^(-)?[0-9]+$`
 	//是否为float正则
-	FLOAT_REG = `^(-)?[0-9]+(.[0-9]+)$`
+	FLOAT_REG = `^(-)?[0-9]+(\.[0-9]+)?$`
 	//年正则
 	YEAR_REG = `(19|2[0-4])\d{2}`
 	//月正则
@@ -59,86 +90,381 @@ var arrayErrorMap = map[string]string{
 	"between":  "array [name] length should be betwween [min] and [max]",
 }
 
+/****************************************************
+ * 字段比较验证错误提示 map
+ ****************************************************/
+var fieldCompareErrorMap = map[string]string{
+	"eq":  "[name] should be equal to [field]",
+	"ne":  "[name] should not be equal to [field]",
+	"gt":  "[name] should be greater than [field]",
+	"gte": "[name] should be greater than or equal to [field]",
+	"lt":  "[name] should be less than [field]",
+	"lte": "[name] should be less than or equal to [field]",
+}
+
 /****************************************************
  * range 验证错误提示 map
  ****************************************************/
 
 var defaultValidator = map[string]interface{}{
-	"required": &RequiredValidator{},
-	"string":   &StringValidator{},
-	"integer":  &IntegerValidator{},
-	"array":    &ArrayValidator{},
-	"email":    &EmailValidator{},
-	"url":      &UrlValidator{},
-	"in":       &InValidator{},
-	"datetime": &DateTimeValidator{},
-	"unique":   &UniqueValidator{},
+	"required":         &RequiredValidator{},
+	"string":           &StringValidator{},
+	"integer":          &IntegerValidator{},
+	"float":            &FloatValidator{},
+	"number":           &NumberValidator{},
+	"array":            &ArrayValidator{},
+	"email":            &EmailValidator{},
+	"url":              &UrlValidator{},
+	"in":               &InValidator{},
+	"datetime":         &DateTimeValidator{},
+	"unique":           &UniqueValidator{},
+	"required_if":      &RequiredIfValidator{},
+	"required_unless":  &RequiredUnlessValidator{},
+	"required_with":    &RequiredWithValidator{},
+	"required_without": &RequiredWithoutValidator{},
+	"eqfield":          &FieldCompareValidator{Op: "eq"},
+	"nefield":          &FieldCompareValidator{Op: "ne"},
+	"gtfield":          &FieldCompareValidator{Op: "gt"},
+	"gtefield":         &FieldCompareValidator{Op: "gte"},
+	"ltfield":          &FieldCompareValidator{Op: "lt"},
+	"ltefield":         &FieldCompareValidator{Op: "lte"},
 }
 
 type goValidator struct {
-	tagName           string
-	skipOnStructEmpty bool
-	validatorSplit    string
-	TitleTag          string
-	validator         map[string]interface{}
+	tagName            string
+	skipOnStructEmpty  bool
+	validatorSplit     string
+	TitleTag           string
+	fieldNameFunc      FieldNameFunc
+	validator          map[string]interface{}
+	structValidators   map[reflect.Type][]StructValidatorF
+	flattenEmbedded    bool
+	maxDepth           int
+	validateUnexported bool
+	callValidatable    bool
+	planCache          sync.Map
+	mutex              sync.RWMutex
 }
 
 type itemParams struct {
-	syncMap         *sync.Map
-	lazyFlag        bool
-	structValidator map[string]Validator
+	syncMap           *sync.Map
+	lazyFlag          bool
+	structValidator   map[string]Validator
+	groups            map[string]bool
+	partial           map[string]bool //ValidatePartial 指定的字段路径
+	partialParents    map[string]bool //ValidatePartial 指定的字段路径的上级路径，需要递归，但不执行验证器
+	except            map[string]bool //ValidateExcept 指定的字段路径
+	ctx               context.Context
+	canceled          bool //context 已取消，验证提前结束
+	aborted           bool //超过最大嵌套层数，验证提前结束
+	depth             int  //当前嵌套层数
+	maxDepth          int  //最大嵌套层数，创建时从 goValidator 读取
+	visited           map[visitKey]bool
+	callValidatable   bool //是否调用 Validatable 接口的 ValidateSelf
+	skipOnStructEmpty bool //创建时从 goValidator 读取，同 goValidator.skipOnStructEmpty
+}
+
+//递归路径上的指针、map、slice，同一地址不同类型(如 struct 和它的第一个字段)分开记录，slice 同一地址不同长度也分开记录
+type visitKey struct {
+	ptr      uintptr
+	typeName reflect.Type
+	len      int
+}
+
+//判断 context 是否已取消或超过最大嵌套层数，返回 true 时停止验证
+func (self *itemParams) done() bool {
+	if !self.canceled && self.ctx.Err() != nil {
+		self.canceled = true
+	}
+	return self.canceled || self.aborted
+}
+
+//记录当前递归路径上的指针、map、slice，已经在路径上时返回 false，避免循环引用导致无限递归
+//返回新记录的 key，当前值验证结束后通过 leave 移除，不在同一路径上的共享指针仍然会分别验证
+func (self *itemParams) visit(val reflect.Value) (keys []visitKey, ok bool) {
+	for {
+		switch val.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice:
+			if val.IsNil() || (val.Kind() == reflect.Slice && val.Len() == 0) {
+				return keys, true
+			}
+			key := visitKey{ptr: val.Pointer(), typeName: val.Type()}
+			if val.Kind() == reflect.Slice {
+				key.len = val.Len()
+			}
+			if self.visited[key] {
+				self.leave(keys)
+				return nil, false
+			}
+			if self.visited == nil {
+				self.visited = make(map[visitKey]bool)
+			}
+			self.visited[key] = true
+			keys = append(keys, key)
+			if val.Kind() != reflect.Ptr {
+				return keys, true
+			}
+		case reflect.Interface:
+			if val.IsNil() {
+				return keys, true
+			}
+		default:
+			return keys, true
+		}
+		val = val.Elem()
+	}
+}
+
+//从当前递归路径中移除 visit 记录的 key
+func (self *itemParams) leave(keys []visitKey) {
+	for _, key := range keys {
+		delete(self.visited, key)
+	}
+}
+
+func (self *itemParams) setGroups(groups []string) {
+	self.groups = make(map[string]bool, len(groups))
+	for _, group := range groups {
+		self.groups[group] = true
+	}
+}
+
+//设置只验证的字段，路径中的下标会被忽略，如 Class[0].Cname 等同于 Class.Cname
+func (self *itemParams) setPartial(fields []string) {
+	self.partial = make(map[string]bool, len(fields))
+	self.partialParents = make(map[string]bool)
+	for _, field := range fields {
+		field = trimPathIndex(field)
+		self.partial[field] = true
+		for num := strings.LastIndex(field, "."); num != -1; num = strings.LastIndex(field, ".") {
+			field = field[:num]
+			self.partialParents[field] = true
+		}
+	}
+}
+
+//设置不验证的字段，路径中的下标会被忽略
+func (self *itemParams) setExcept(fields []string) {
+	self.except = make(map[string]bool, len(fields))
+	for _, field := range fields {
+		self.except[trimPathIndex(field)] = true
+	}
+}
+
+//判断字段是否需要执行验证器(runRules)、是否需要继续递归验证(recurse)
+//namePath 为 struct 字段名路径，path 为 SetFieldNameFunc 生成的字段路径，两种路径都可以匹配
+func (self *itemParams) filterField(namePath, path string) (runRules, recurse bool) {
+	if self.partial == nil && self.except == nil {
+		return true, true
+	}
+	paths := []string{namePath, strings.TrimPrefix(trimPathIndex(path), ".")}
+	for _, p := range paths {
+		if self.except != nil && matchPathPrefix(self.except, p) {
+			return false, false
+		}
+	}
+	if self.partial == nil {
+		return true, true
+	}
+	for _, p := range paths {
+		if matchPathPrefix(self.partial, p) {
+			return true, true
+		}
+	}
+	for _, p := range paths {
+		if self.partialParents[p] {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+//判断验证器是否属于当前验证的分组，没有分组的验证器总是执行
+func (self *itemParams) inGroups(rule *rulePlan) bool {
+	if len(rule.groups) == 0 {
+		return true
+	}
+	for _, group := range rule.groups {
+		if self.groups[group] {
+			return true
+		}
+	}
+	return false
 }
 
 func New() *goValidator {
+	//每个实例持有一份验证器的拷贝，SetValidator 不会影响其他实例
+	validator := make(map[string]interface{}, len(defaultValidator))
+	for validatorK, validatorV := range defaultValidator {
+		validator[validatorK] = validatorV
+	}
 	return &goValidator{
 		tagName:           "validate",
 		TitleTag:          "title",
 		skipOnStructEmpty: true,
 		validatorSplit:    "||",
-		validator:         defaultValidator,
+		fieldNameFunc:     StructFieldName,
+		validator:         validator,
+		flattenEmbedded:   true,
+		callValidatable:   true,
 	}
 }
 
 func (self *goValidator) SetTag(tag string) *goValidator {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
 	self.tagName = tag
+	self.resetPlanCache()
 	return self
 }
 
 func (self *goValidator) SetTitleTag(titleTag string) *goValidator {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
 	self.TitleTag = titleTag
+	self.resetPlanCache()
+	return self
+}
+
+//设置错误中字段路径的字段名生成函数，默认为 StructFieldName
+func (self *goValidator) SetFieldNameFunc(f FieldNameFunc) *goValidator {
+	if f == nil {
+		f = StructFieldName
+	}
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.fieldNameFunc = f
+	self.resetPlanCache()
+	return self
+}
+
+//设置是否将嵌入的 struct 的字段提升到上级路径中，和 encoding/json 一致，默认为 true
+//为 false 时，嵌入的 struct 作为单独的字段，路径中带有类型名，如 Base.Name
+func (self *goValidator) SetFlattenEmbedded(flatten bool) *goValidator {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.flattenEmbedded = flatten
+	self.resetPlanCache()
+	return self
+}
+
+//设置是否通过反射递归验证未导出的 struct、map、slice、array 字段，默认为 false，不递归验证
+//未导出字段上配置的验证器总是执行；通过未导出字段获取的值无法调用 Interface()，不会执行 struct 级别验证器和 Validatable 接口
+func (self *goValidator) SetValidateUnexported(validate bool) *goValidator {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.validateUnexported = validate
+	self.resetPlanCache()
+	return self
+}
+
+//设置是否自动调用 Validatable 接口的 ValidateSelf，默认为 true
+func (self *goValidator) SetCallValidatable(call bool) *goValidator {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.callValidatable = call
+	return self
+}
+
+//设置最大递归层数，顶层的值为第 1 层，struct 字段、slice 和 array 的元素、map 的值每递归一次加 1 层，超过时停止验证并返回错误，小于等于 0 时不限制，默认为 0
+func (self *goValidator) SetMaxDepth(depth int) *goValidator {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.maxDepth = depth
 	return self
 }
 
 func (self *goValidator) SetSkipOnStructEmpty(skip bool) *goValidator {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
 	self.skipOnStructEmpty = skip
 	return self
 }
 
 func (self *goValidator) SetValidatorSplit(str string) *goValidator {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
 	self.validatorSplit = str
+	self.resetPlanCache()
 	return self
 }
 
-func (self *goValidator) SetValidator(validatorK string, validator interface{}) *goValidator {
+//设置自定义验证器，验证器配置错误(如不合法的自定义正则)时返回 error，且不会生效
+func (self *goValidator) SetValidator(validatorK string, validator interface{}) error {
+	if err := checkValidator(validatorK, validator); err != nil {
+		return err
+	}
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
 	self.validator[validatorK] = validator
-	return self
+	self.resetPlanCache()
+	return nil
 }
 
-func (self *goValidator) SetValidators(validatorMap map[string]interface{}) *goValidator {
+//批量设置自定义验证器，任意一个验证器配置错误时返回 error，且全部不会生效
+func (self *goValidator) SetValidators(validatorMap map[string]interface{}) error {
+	for validatorK, validatorV := range validatorMap {
+		if err := checkValidator(validatorK, validatorV); err != nil {
+			return err
+		}
+	}
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
 	for validatorK, validatorV := range validatorMap {
 		self.validator[validatorK] = validatorV
 	}
+	self.resetPlanCache()
+	return nil
+}
+
+//删除验证器
+func (self *goValidator) RemoveValidator(validatorK string) *goValidator {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	delete(self.validator, validatorK)
+	self.resetPlanCache()
 	return self
 }
 
+//判断验证器是否存在
+func (self *goValidator) HasValidator(validatorK string) bool {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+	_, ok := self.validator[validatorK]
+	return ok
+}
+
+//获取全部验证器的 key，按字典序排列
+func (self *goValidator) Validators() []string {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+	keys := make([]string, 0, len(self.validator))
+	for validatorK := range self.validator {
+		keys = append(keys, validatorK)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//创建单次验证的参数，验证过程中使用的配置在这里读取，避免和 Set 方法并发读写
+func (self *goValidator) newItemParams(lazyFlag bool) *itemParams {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+	return &itemParams{
+		syncMap:           &sync.Map{},
+		lazyFlag:          lazyFlag,
+		structValidator:   make(map[string]Validator),
+		ctx:               context.Background(),
+		callValidatable:   self.callValidatable,
+		maxDepth:          self.maxDepth,
+		skipOnStructEmpty: self.skipOnStructEmpty,
+	}
+}
+
 func (self *goValidator) LazyValidate(s interface{}) (err error) {
 	parentKey := "validate"
-	params := &itemParams{
-		syncMap:         &sync.Map{},
-		lazyFlag:        true,
-		structValidator: make(map[string]Validator),
-	}
-	errArr := self.validate(s, parentKey, params)
+	params := self.newItemParams(true)
+	errArr := self.validate(s, parentKey, "", "", params)
 	if errArr != nil {
 		err = errArr[0]
 	}
@@ -147,23 +473,231 @@ func (self *goValidator) LazyValidate(s interface{}) (err error) {
 
 func (self *goValidator) Validate(s interface{}) (err []error) {
 	parentKey := "validate"
-	params := &itemParams{
-		syncMap:         &sync.Map{},
-		lazyFlag:        false,
-		structValidator: make(map[string]Validator),
+	params := self.newItemParams(false)
+	err = self.validate(s, parentKey, "", "", params)
+	return
+}
+
+//带 context 验证，context 取消后停止验证并返回 context 的错误，如果出现错误，不继续执行，并将错误返回
+//context 会传给实现了 ValidatorCtx 的验证器，其他验证器可以通过 params["ctx"] 获取
+func (self *goValidator) LazyValidateCtx(ctx context.Context, s interface{}) (err error) {
+	parentKey := "validate"
+	params := self.newItemParams(true)
+	if ctx != nil {
+		params.ctx = ctx
+	}
+	errArr := self.validate(s, parentKey, "", "", params)
+	if errArr != nil {
+		err = errArr[0]
+	} else if params.canceled {
+		err = toValidationError(params.ctx.Err(), "", "", VALIDATOR_CTX, nil, reflect.Value{})
 	}
-	err = self.validate(s, parentKey, params)
 	return
 }
 
-func (self *goValidator) validate(s interface{}, parentKey string, params *itemParams) (returnErr []error) {
-	var errArr []error
+//带 context 验证，context 取消后停止验证，并在已有错误之后加上 context 的错误，如果出现错误，会继续执行，并将错误全部返回
+func (self *goValidator) ValidateCtx(ctx context.Context, s interface{}) (err []error) {
+	parentKey := "validate"
+	params := self.newItemParams(false)
+	if ctx != nil {
+		params.ctx = ctx
+	}
+	err = self.validate(s, parentKey, "", "", params)
+	if params.canceled {
+		err = append(err, toValidationError(params.ctx.Err(), "", "", VALIDATOR_CTX, nil, reflect.Value{}))
+	}
+	return
+}
+
+//只验证指定的字段，字段为 . 分隔的 struct 字段名或 SetFieldNameFunc 生成的字段名路径，如 Name、Class.Cname，字段不存在时返回错误，如果出现错误，不继续执行，并将错误返回
+func (self *goValidator) LazyValidatePartial(s interface{}, fields ...string) (err error) {
+	parentKey := "validate"
+	params := self.newItemParams(true)
+	fields, errArr := self.resolveFieldPaths(s, VALIDATOR_PARTIAL, fields)
+	if errArr == nil {
+		params.setPartial(fields)
+		errArr = self.validate(s, parentKey, "", "", params)
+	}
+	if errArr != nil {
+		err = errArr[0]
+	}
+	return
+}
+
+//只验证指定的字段，字段为 . 分隔的 struct 字段名或 SetFieldNameFunc 生成的字段名路径，如 Name、Class.Cname，字段不存在时返回错误，如果出现错误，会继续执行，并将错误全部返回
+func (self *goValidator) ValidatePartial(s interface{}, fields ...string) (err []error) {
+	parentKey := "validate"
+	params := self.newItemParams(false)
+	if fields, err = self.resolveFieldPaths(s, VALIDATOR_PARTIAL, fields); err != nil {
+		return
+	}
+	params.setPartial(fields)
+	err = self.validate(s, parentKey, "", "", params)
+	return
+}
+
+//不验证指定的字段，字段为 . 分隔的 struct 字段名或 SetFieldNameFunc 生成的字段名路径，如 Password、Class.Cname，字段不存在时返回错误，如果出现错误，不继续执行，并将错误返回
+func (self *goValidator) LazyValidateExcept(s interface{}, fields ...string) (err error) {
+	parentKey := "validate"
+	params := self.newItemParams(true)
+	fields, errArr := self.resolveFieldPaths(s, VALIDATOR_EXCEPT, fields)
+	if errArr == nil {
+		params.setExcept(fields)
+		errArr = self.validate(s, parentKey, "", "", params)
+	}
+	if errArr != nil {
+		err = errArr[0]
+	}
+	return
+}
+
+//不验证指定的字段，字段为 . 分隔的 struct 字段名或 SetFieldNameFunc 生成的字段名路径，如 Password、Class.Cname，字段不存在时返回错误，如果出现错误，会继续执行，并将错误全部返回
+func (self *goValidator) ValidateExcept(s interface{}, fields ...string) (err []error) {
+	parentKey := "validate"
+	params := self.newItemParams(false)
+	if fields, err = self.resolveFieldPaths(s, VALIDATOR_EXCEPT, fields); err != nil {
+		return
+	}
+	params.setExcept(fields)
+	err = self.validate(s, parentKey, "", "", params)
+	return
+}
+
+//将 ValidatePartial、ValidateExcept 指定的字段路径转换为 struct 字段名路径，不存在的字段返回错误，Rule 为 partial 或 except
+func (self *goValidator) resolveFieldPaths(s interface{}, rule string, fields []string) (paths []string, err []error) {
 	typeObj := reflect.TypeOf(s)
-	typeValue := reflect.ValueOf(s)
-	if typeObj.Kind() == reflect.Ptr {
-		typeObj = typeObj.Elem()
-		typeValue = typeValue.Elem()
+	if typeObj == nil {
+		return fields, nil
+	}
+	for _, field := range fields {
+		resolved := self.resolveFieldPath(typeObj, strings.Split(trimPathIndex(field), "."), nil)
+		if len(resolved) == 0 {
+			err = append(err, &ValidationError{
+				Field: field,
+				Path:  field,
+				Rule:  rule,
+				Msg:   fmt.Sprintf("%v field %v not exist", rule, field),
+			})
+			continue
+		}
+		paths = append(paths, resolved...)
+	}
+	return
+}
+
+//在类型中查找字段路径，返回对应的 struct 字段名路径，路径中的字段名可以是 struct 字段名或 SetFieldNameFunc 生成的字段名
+//interface 的值无法通过类型判断，之后的路径原样返回，embeds 记录已经查找过的嵌入 struct，避免嵌入自身时无限递归
+func (self *goValidator) resolveFieldPath(typeObj reflect.Type, names []string, embeds map[reflect.Type]bool) (paths []string) {
+	if len(names) == 0 {
+		return []string{""}
+	}
+	for typeObj.Kind() != reflect.Struct {
+		switch typeObj.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			typeObj = typeObj.Elem()
+		case reflect.Interface:
+			return []string{strings.Join(names, ".")}
+		default:
+			return nil
+		}
+	}
+	for _, field := range self.getStructPlan(typeObj).fields {
+		//提升到上级路径中的嵌入 struct，在它的字段中继续查找
+		if field.embedded {
+			if !embeds[field.field.Type] {
+				if embeds == nil {
+					embeds = make(map[reflect.Type]bool)
+				}
+				embeds[field.field.Type] = true
+				paths = append(paths, self.resolveFieldPath(field.field.Type, names, embeds)...)
+			}
+			continue
+		}
+		if names[0] != field.field.Name && names[0] != field.pathName {
+			continue
+		}
+		for _, path := range self.resolveFieldPath(field.field.Type, names[1:], nil) {
+			paths = append(paths, strings.TrimSuffix(joinPath(field.field.Name, path), "."))
+		}
+	}
+	return
+}
+
+//对单个值进行验证，rules 的格式和 struct tag 一致，如 required||email，如果出现错误，会继续执行，并将错误全部返回
+func (self *goValidator) ValidateVar(val interface{}, rules string) (err []error) {
+	return self.ValidateVarWithName(val, VALIDATOR_VAR_NAME, rules)
+}
+
+//对单个值进行验证，name 为错误提示中使用的名称，如果出现错误，会继续执行，并将错误全部返回
+func (self *goValidator) ValidateVarWithName(val interface{}, name, rules string) (err []error) {
+	parentKey := "validate"
+	params := self.newItemParams(false)
+	err, _ = self.validateVar(reflect.ValueOf(val), name, rules, parentKey+"_"+name, name, reflect.Value{}, params)
+	return
+}
+
+//按 rules 验证 map，rules 和 data 的结构一致，值为验证规则字符串(格式和 struct tag 一致)或嵌套的 rules，如果出现错误，会继续执行，并将错误全部返回
+func (self *goValidator) ValidateMap(data map[string]interface{}, rules map[string]interface{}) (err []error) {
+	parentKey := "validate"
+	params := self.newItemParams(false)
+	err = self.validateMap(reflect.ValueOf(data), rules, parentKey, "", params)
+	return
+}
+
+//按分组验证，如果出现错误，不继续执行，并将错误返回
+func (self *goValidator) LazyValidateGroups(s interface{}, groups ...string) (err error) {
+	parentKey := "validate"
+	params := self.newItemParams(true)
+	params.setGroups(groups)
+	errArr := self.validate(s, parentKey, "", "", params)
+	if errArr != nil {
+		err = errArr[0]
+	}
+	return
+}
+
+//按分组验证，tag 中没有分组的验证器总是执行，带分组的验证器(如 create:required)只在指定的分组中执行
+func (self *goValidator) ValidateGroups(s interface{}, groups ...string) (err []error) {
+	parentKey := "validate"
+	params := self.newItemParams(false)
+	params.setGroups(groups)
+	err = self.validate(s, parentKey, "", "", params)
+	return
+}
+
+func (self *goValidator) validate(s interface{}, parentKey, path, namePath string, params *itemParams) (returnErr []error) {
+	return self.validateValue(reflect.ValueOf(s), parentKey, path, namePath, params)
+}
+
+//path 为错误中的字段路径，namePath 为不带下标的 struct 字段名路径，如 Class.Cname，用于 ValidatePartial、ValidateExcept
+//通过反射递归验证，不调用 Interface()，未导出的字段同样可以验证
+func (self *goValidator) validateValue(val reflect.Value, parentKey, path, namePath string, params *itemParams) (returnErr []error) {
+	var errArr []error
+	//指针、map、slice 已经在当前递归路径上时为循环引用，不再验证
+	keys, ok := params.visit(val)
+	if !ok {
+		return
+	}
+	defer params.leave(keys)
+	typeValue, isNil := indirectValue(val)
+	if !typeValue.IsValid() || isNil {
+		return
 	}
+	//每一层递归都计入嵌套层数，超过最大嵌套层数时停止验证
+	params.depth++
+	defer func() {
+		params.depth--
+	}()
+	if params.maxDepth > 0 && params.depth > params.maxDepth {
+		params.aborted = true
+		return append(returnErr, &ValidationError{
+			Path: path,
+			Rule: VALIDATOR_MAX_DEPTH,
+			Args: []string{strconv.Itoa(params.maxDepth)},
+			Msg:  fmt.Sprintf("%v exceeds max depth %v", path, params.maxDepth),
+		})
+	}
+	typeObj := typeValue.Type()
 	switch typeObj.Kind() {
 	case reflect.Map:
 		//判断是否需要递归
@@ -172,12 +706,11 @@ func (self *goValidator) validate(s interface{}, parentKey string, params *itemP
 		}
 		mapKeys := typeValue.MapKeys()
 		for _, key := range mapKeys {
-			tmpParentKey := fmt.Sprintf("%v_%v", parentKey, key.String())
-			mapItem := typeValue.MapIndex(key)
-			if !mapItem.CanInterface() {
-				continue
+			if params.done() {
+				return
 			}
-			errArr = self.validate(mapItem.Interface(), tmpParentKey, params)
+			tmpParentKey := fmt.Sprintf("%v_%v", parentKey, key)
+			errArr = self.validateValue(typeValue.MapIndex(key), tmpParentKey, indexPath(path, formatMapKey(key)), namePath, params)
 			if len(errArr) > 0 {
 				returnErr = append(returnErr, errArr...)
 				if params.lazyFlag {
@@ -190,8 +723,11 @@ func (self *goValidator) validate(s interface{}, parentKey string, params *itemP
 		//判断是否需要递归
 		if ok, fieldNum := checkArrayValueIsMulti(typeValue); ok {
 			for i := 0; i < fieldNum; i++ {
+				if params.done() {
+					return
+				}
 				tmpParentKey := fmt.Sprintf("%v_%v", parentKey, i)
-				errArr = self.validate(typeValue.Index(i).Interface(), tmpParentKey, params)
+				errArr = self.validateValue(typeValue.Index(i), tmpParentKey, indexPath(path, strconv.Itoa(i)), namePath, params)
 				if len(errArr) > 0 {
 					returnErr = append(returnErr, errArr...)
 					if params.lazyFlag {
@@ -205,25 +741,44 @@ func (self *goValidator) validate(s interface{}, parentKey string, params *itemP
 	case reflect.Struct:
 		numField := typeValue.NumField()
 		if numField <= 0 {
-			if self.skipOnStructEmpty {
+			if params.skipOnStructEmpty {
 				return
 			}
-			returnErr = append(returnErr, fmt.Errorf(STRUCT_EMPTY, typeObj.Name()))
+			err := fmt.Errorf(STRUCT_EMPTY, typeObj.Name())
+			returnErr = append(returnErr, toValidationError(err, typeObj.Name(), path, VALIDATOR_STRUCT, nil, typeValue))
 			return
 		}
-
-		for i := 0; i < numField; i++ {
-			fieldInfo := typeValue.Field(i)
-			fieldTypeInfo := typeValue.Type().Field(i)
+		plan := self.getStructPlan(typeObj)
+		for _, field := range plan.fields {
+			if params.done() {
+				return
+			}
+			//指针类型验证指向的值，nil 指针视为未设置
+			fieldValue := typeValue.Field(field.index)
+			fieldInfo, isNil := indirectValue(fieldValue)
+			fieldTypeInfo := field.field
 			fieldType := fieldInfo.Type().Kind()
-			tag := fieldTypeInfo.Tag.Get(self.tagName)
-			if tag != "" {
+			fieldPath := joinPath(path, field.pathName)
+			fieldNamePath := joinPath(namePath, field.field.Name)
+			//ValidatePartial、ValidateExcept 过滤字段
+			runRules, recurse := params.filterField(fieldNamePath, fieldPath)
+			//嵌入的 struct 的字段提升到上级路径中，由提升后的字段各自过滤
+			recursePath, recurseNamePath := fieldPath, fieldNamePath
+			if field.embedded {
+				recursePath, recurseNamePath = path, namePath
+				recurse = true
+			}
+			if !recurse {
+				continue
+			}
+			if field.tag != "" && runRules {
 				//没有配置 required，并且 field 为 0 值的，直接跳过
 				isZeroValue := isZeroValue(fieldInfo)
-				if isZeroValue && !strings.Contains(tag, "required") && !self.skipOnStructEmpty {
+				if isZeroValue && !field.required && !params.skipOnStructEmpty {
 					continue
 				}
-				errArr = self.validateValueFromTag(tag, parentKey, params, fieldTypeInfo, fieldInfo)
+				var omitted bool
+				errArr, omitted = self.validateField(field, parentKey, fieldPath, params, typeValue, fieldInfo, isNil, !isNil && isPresentPtr(fieldValue))
 				if len(errArr) > 0 {
 					returnErr = append(returnErr, errArr...)
 					if params.lazyFlag {
@@ -231,18 +786,22 @@ func (self *goValidator) validate(s interface{}, parentKey string, params *itemP
 					}
 					continue
 				}
+				//omitempty、omitnil 生效时，不再递归验证
+				if omitted {
+					continue
+				}
+			}
+			//未导出的字段默认不递归验证
+			if !field.recurse {
+				continue
 			}
 			//判断是否需要递归
 			if ok, fieldNum := checkArrayValueIsMulti(fieldInfo); ok {
 				if fieldInfo.Type().Kind() == reflect.Map {
 					mapKeys := fieldInfo.MapKeys()
 					for _, key := range mapKeys {
-						tmpParentKey := fmt.Sprintf("%v_%v", parentKey, key.String())
-						mapItem := fieldInfo.MapIndex(key)
-						if !mapItem.CanInterface() {
-							continue
-						}
-						errArr = self.validate(mapItem.Interface(), tmpParentKey, params)
+						tmpParentKey := fmt.Sprintf("%v_%v", parentKey, key)
+						errArr = self.validateValue(fieldInfo.MapIndex(key), tmpParentKey, indexPath(fieldPath, formatMapKey(key)), fieldNamePath, params)
 						if len(errArr) > 0 {
 							returnErr = append(returnErr, errArr...)
 							if params.lazyFlag {
@@ -255,7 +814,7 @@ func (self *goValidator) validate(s interface{}, parentKey string, params *itemP
 				}
 				for i := 0; i < fieldNum; i++ {
 					tmpParentKey := fmt.Sprintf("%v_%v", parentKey, fieldTypeInfo.Name)
-					errArr = self.validate(fieldInfo.Index(i).Interface(), tmpParentKey, params)
+					errArr = self.validateValue(fieldInfo.Index(i), tmpParentKey, indexPath(fieldPath, strconv.Itoa(i)), fieldNamePath, params)
 					if len(errArr) > 0 {
 						returnErr = append(returnErr, errArr...)
 						if params.lazyFlag {
@@ -266,9 +825,9 @@ func (self *goValidator) validate(s interface{}, parentKey string, params *itemP
 				}
 			}
 
-			if fieldType == reflect.Struct {
+			if fieldType == reflect.Struct || isValidatable(fieldInfo.Type()) {
 				tmpParentKey := fmt.Sprintf("%v_%v", parentKey, fieldTypeInfo.Name)
-				errArr = self.validate(fieldInfo.Interface(), tmpParentKey, params)
+				errArr = self.validateValue(fieldValue, tmpParentKey, recursePath, recurseNamePath, params)
 				if len(errArr) > 0 {
 					returnErr = append(returnErr, errArr...)
 					if params.lazyFlag {
@@ -278,80 +837,187 @@ func (self *goValidator) validate(s interface{}, parentKey string, params *itemP
 				}
 			}
 		}
+		//字段验证之后执行 struct 级别的验证器，ValidatePartial 只选择了部分字段时不执行
+		if runRules, _ := params.filterField(namePath, path); len(plan.structValidators) > 0 && runRules {
+			returnErr = append(returnErr, self.validateStruct(plan.structValidators, typeValue, path, params)...)
+		}
+	}
+	//最后调用 Validatable 接口的 ValidateSelf
+	if validatable, ok := asValidatable(typeValue); ok && params.callValidatable && (len(returnErr) == 0 || !params.lazyFlag) {
+		if runRules, _ := params.filterField(namePath, path); runRules {
+			if err := validatable.ValidateSelf(); err != nil {
+				returnErr = append(returnErr, toValidationError(err, typeObj.Name(), path, VALIDATOR_VALIDATABLE, nil, typeValue))
+			}
+		}
 	}
 	return
 }
 
-//根据 tag 申请验证器进行验证
-func (self *goValidator) validateValueFromTag(tag, parentKey string, params *itemParams, fieldTypeInfo reflect.StructField, fieldInfo reflect.Value) (returnErr []error) {
-	validatorT := reflect.TypeOf((*Validator)(nil)).Elem()
-	validatorFT := reflect.TypeOf((*ValidatorF)(nil)).Elem()
-	title := fieldTypeInfo.Tag.Get(self.TitleTag)
-	args := strings.Split(tag, self.validatorSplit)
-	for _, argTmp := range args {
-		var vK = argTmp
-		var vArgs []string
-		//查找是否含有赋值符号
-		num := strings.Index(argTmp, VALIDATOR_VALUE_SIGN)
-		//等于 -1,说明不是像 required 这种不含有 = 号的，而是 array=1,2 这种的
-		if num != -1 {
-			vK = argTmp[0:num]
-			vArgs = strings.Split(argTmp[num+1:], VALIDATOR_RANGE_SPLIT)
-		}
-
-		if _, ok := self.validator[vK]; !ok {
-			returnErr = append(returnErr, fmt.Errorf("validator %v not exist", vK))
+//根据字段的验证计划申请验证器进行验证
+//parent 为字段所在的 struct，供 eqfield 等需要获取同级字段的验证器使用
+func (self *goValidator) validateField(field *fieldPlan, parentKey, path string, params *itemParams, parent, fieldInfo reflect.Value, isNil, present bool) (returnErr []error, omitted bool) {
+	return self.validateRules(field.rules, field.name, parentKey+"_"+field.field.Name, path, params, parent, fieldInfo, isNil, present)
+}
+
+//对单个值执行验证器，parent 为值所属的 struct 或 map，同级字段比较类的验证器从 parent 中查找字段
+//skipped 为 true 表示值为零值且没有 required 类验证器，或者 omitempty、omitnil 生效，没有执行验证器
+func (self *goValidator) validateVar(val reflect.Value, name, rules, allKey, path string, parent reflect.Value, params *itemParams) (returnErr []error, skipped bool) {
+	if rules == "" {
+		return
+	}
+	fieldInfo, isNil := indirectValue(val)
+	if !fieldInfo.IsValid() {
+		isNil = true
+	}
+	ruleList := self.getVarRules(rules)
+	if isZeroValue(fieldInfo) && !hasRequiredRule(ruleList) && !params.skipOnStructEmpty {
+		return nil, true
+	}
+	return self.validateRules(ruleList, name, allKey, path, params, parent, fieldInfo, isNil, !isNil && isPresentPtr(val))
+}
+
+//按 rules 验证 map，rules 的值为验证规则字符串时验证对应的值，为 map[string]interface{} 时验证嵌套的 map 或 map 组成的 slice
+func (self *goValidator) validateMap(data reflect.Value, rules map[string]interface{}, parentKey, path string, params *itemParams) (returnErr []error) {
+	var errArr []error
+	keys := make([]string, 0, len(rules))
+	for key := range rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		//嵌套的值本身的规则在 validateMapItem 中执行
+		if key == VALIDATOR_MAP_SELF {
+			continue
+		}
+		keyPath := joinPath(path, key)
+		val := reflect.Value{}
+		if data.IsValid() {
+			val = data.MapIndex(reflect.ValueOf(key).Convert(data.Type().Key()))
+		}
+		switch rule := rules[key].(type) {
+		case string:
+			errArr, _ = self.validateVar(val, key, rule, parentKey+"_"+key, keyPath, data, params)
+		case map[string]interface{}:
+			errArr = self.validateMapItem(val, key, rule, parentKey+"_"+key, keyPath, data, params)
+		default:
+			err := formatError("rule of [name] error", map[string]string{"name": key})
+			errArr = []error{toValidationError(err, key, keyPath, "", nil, val)}
+		}
+		if len(errArr) > 0 {
+			returnErr = append(returnErr, errArr...)
 			if params.lazyFlag {
 				return
 			}
-			continue
 		}
+	}
+	return
+}
 
-		var validator Validator
-		tmpValidator := self.validator[vK]
-		vT := reflect.TypeOf(tmpValidator)
-		vV := reflect.ValueOf(tmpValidator)
-		if vT.ConvertibleTo(validatorFT) {
-			tmpV, ok := tmpValidator.(func(params map[string]interface{}, val reflect.Value, args ...string) (bool, error))
-			if !ok {
-				returnErr = append(returnErr, fmt.Errorf("validator %v error", vK))
+//按嵌套的 rules 验证 map 中的值，rules 中 key 为 "" 的规则验证值本身，如 {"": "required", "cid": "integer"}
+//值本身的规则为零值跳过或 omitempty、omitnil 生效时，不再验证嵌套的值；没有值本身的规则时，值不存在或为 nil 视为空的 map，嵌套的 required 类验证器会执行
+func (self *goValidator) validateMapItem(val reflect.Value, name string, rules map[string]interface{}, parentKey, path string, parent reflect.Value, params *itemParams) (returnErr []error) {
+	if rule, ok := rules[VALIDATOR_MAP_SELF]; ok {
+		tag, ok := rule.(string)
+		if !ok {
+			err := formatError("rule of [name] error", map[string]string{"name": name})
+			return append(returnErr, toValidationError(err, name, path, "", nil, val))
+		}
+		errArr, skipped := self.validateVar(val, name, tag, parentKey, path, parent, params)
+		if len(errArr) > 0 || skipped {
+			return errArr
+		}
+	}
+	return self.validateMapValue(val, name, rules, parentKey, path, params)
+}
+
+//验证嵌套的 map，值为 slice、array 时验证每个元素
+func (self *goValidator) validateMapValue(val reflect.Value, name string, rules map[string]interface{}, parentKey, path string, params *itemParams) (returnErr []error) {
+	val, isNil := indirectValue(val)
+	if !val.IsValid() || isNil {
+		//使用 nil map，eqfield 等验证器查找同级字段时视为零值
+		return self.validateMap(reflect.ValueOf(map[string]interface{}(nil)), rules, parentKey, path, params)
+	}
+	switch val.Kind() {
+	case reflect.Map:
+		if val.Type().Key().Kind() == reflect.String {
+			return self.validateMap(val, rules, parentKey, path, params)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			errArr := self.validateMapValue(val.Index(i), name, rules, parentKey, indexPath(path, strconv.Itoa(i)), params)
+			if len(errArr) > 0 {
+				returnErr = append(returnErr, errArr...)
 				if params.lazyFlag {
 					return
 				}
-				continue
 			}
-			validator = ValidatorF(tmpV)
-		} else if vT.Implements(validatorT) {
-			if cacheValidator, ok := params.structValidator[vK]; ok {
-				validator = cacheValidator
-			} else if vT.Kind() == reflect.Ptr {
-				// defaultValidator 传的结构体指针，在并发条件下，会导致结构体值被覆盖，需要做对象拷贝
-				baseValidator := reflect.New(vV.Elem().Type())
-				baseValidator.Elem().Set(vV.Elem())
-				validator = baseValidator.Interface().(Validator)
-				params.structValidator[vK] = validator
-			} else {
-				validator = tmpValidator.(Validator)
-			}
-		} else {
-			returnErr = append(returnErr, fmt.Errorf("validator %v error", vK))
+		}
+		return
+	}
+	err := formatError("[name] is not a map", map[string]string{"name": name})
+	return append(returnErr, toValidationError(err, name, path, "", nil, val))
+}
+
+//依次执行验证器，遇到 dive 时，后边的验证器对 slice、array、map 的每个元素执行
+//present 为 true 表示值通过非 nil 指针获取，required 类验证器视为已设置，即使指向零值
+func (self *goValidator) validateRules(rules []*rulePlan, name, allKey, path string, params *itemParams, parent, val reflect.Value, isNil, present bool) (returnErr []error, omitted bool) {
+	for i, rule := range rules {
+		//配置错误不受分组影响，总是返回
+		if rule.err == nil && !params.inGroups(rule) {
+			continue
+		}
+		//dive 配置错误时，后边的验证器无法确定作用于哪个值，不再执行
+		if rule.err != nil && rule.key == VALIDATOR_DIVE {
+			return append(returnErr, toValidationError(rule.err, name, path, rule.key, rule.args, val)), false
+		}
+		switch rule.key {
+		case VALIDATOR_OMIT_EMPTY:
+			if isNil || isZeroValue(val) {
+				omitted = true
+				return
+			}
+			continue
+		case VALIDATOR_OMIT_NIL:
+			if isNil || isNilValue(val) {
+				omitted = true
+				return
+			}
+			continue
+		case VALIDATOR_DIVE:
+			if !isNil {
+				returnErr = append(returnErr, self.validateDive(rules[i+1:], name, allKey, path, params, parent, val)...)
+			}
+			return
+		}
+		//值为 nil 指针时，只执行 required 类的验证器
+		if isNil && !rule.required {
+			continue
+		}
+		//验证器不存在、tag 解析错误等配置错误，同样返回 *ValidationError
+		if rule.err != nil {
+			returnErr = append(returnErr, toValidationError(rule.err, name, path, rule.key, rule.args, val))
 			if params.lazyFlag {
 				return
 			}
 			continue
 		}
-		name := fieldTypeInfo.Name
-		if title != "" {
-			name = title
+		if rule.validator == nil && rule.op == "" {
+			continue
+		}
+		if params.done() {
+			return
 		}
 		var innerParams = map[string]interface{}{
 			"name":    name,
 			"syncMap": params.syncMap,
-			"allKey":  parentKey + "_" + fieldTypeInfo.Name,
+			"allKey":  allKey,
+			"parent":  parent,
+			"ctx":     params.ctx,
+			"present": present,
 		}
-		valid, err := validator.Validate(innerParams, fieldInfo, vArgs...)
+		valid, err := self.evalRule(rule, params, innerParams, val)
 		if valid == false {
-			returnErr = append(returnErr, err)
+			returnErr = append(returnErr, toValidationError(err, name, path, rule.key, rule.args, val))
 			if params.lazyFlag {
 				return
 			}
@@ -360,3 +1026,114 @@ func (self *goValidator) validateValueFromTag(tag, parentKey string, params *ite
 	}
 	return
 }
+
+//执行验证器，组合验证器按运算符计算结果
+func (self *goValidator) evalRule(rule *rulePlan, params *itemParams, innerParams map[string]interface{}, val reflect.Value) (bool, error) {
+	switch rule.op {
+	case VALIDATOR_NOT:
+		//只对验证不通过取反，参数错误、字段不存在、类型不支持等配置错误直接返回
+		valid, err := self.evalRule(rule.items[0], params, innerParams, val)
+		if errors.Is(err, ErrValidatorConfig) {
+			return false, err
+		}
+		if valid {
+			return false, formatError("[name] should not match [rule]", map[string]string{
+				"name": innerParams["name"].(string),
+				"rule": strings.TrimPrefix(rule.key, VALIDATOR_NOT),
+			})
+		}
+		return true, nil
+	case VALIDATOR_OR:
+		//全部验证失败时，错误提示用 or 连接
+		var msgs []string
+		for _, item := range rule.items {
+			valid, err := self.evalRule(item, params, innerParams, val)
+			if errors.Is(err, ErrValidatorConfig) {
+				return false, err
+			}
+			if valid {
+				return true, nil
+			}
+			if err != nil {
+				msgs = append(msgs, err.Error())
+			}
+		}
+		if len(msgs) == 0 {
+			return false, nil
+		}
+		return false, &ValidationError{Msg: strings.Join(msgs, " or ")}
+	case VALIDATOR_AND:
+		for _, item := range rule.items {
+			if valid, err := self.evalRule(item, params, innerParams, val); !valid {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return rule.getValidator(params).Validate(innerParams, val, rule.args...)
+}
+
+//对 slice、array、map 的每个元素执行 dive 后边的验证器，错误路径中带有元素的下标或 key
+func (self *goValidator) validateDive(rules []*rulePlan, name, allKey, path string, params *itemParams, parent, val reflect.Value) (returnErr []error) {
+	//同一层级的元素共用 allKey，unique 可以判断元素之间是否重复
+	allKey = allKey + "_" + VALIDATOR_DIVE
+	//dive||keys||...||endkeys||...，拆分出对 map key 执行的验证器
+	var keyRules []*rulePlan
+	if len(rules) > 0 && rules[0].key == VALIDATOR_KEYS {
+		if rules[0].err != nil {
+			return append(returnErr, toValidationError(rules[0].err, name, path, VALIDATOR_KEYS, nil, val))
+		}
+		if val.Kind() != reflect.Map {
+			err := formatError("[name] is not a map, can not validate keys", map[string]string{"name": name})
+			return append(returnErr, toValidationError(err, name, path, VALIDATOR_KEYS, nil, val))
+		}
+		for i, rule := range rules {
+			if rule.key == VALIDATOR_END_KEYS {
+				if rule.err != nil {
+					return append(returnErr, toValidationError(rule.err, name, path, VALIDATOR_END_KEYS, nil, val))
+				}
+				keyRules, rules = rules[1:i], rules[i+1:]
+				break
+			}
+		}
+	}
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			elem, elemNil := indirectValue(val.Index(i))
+			errArr, _ := self.validateRules(rules, name, allKey, indexPath(path, strconv.Itoa(i)), params, parent, elem, elemNil, !elemNil && isPresentPtr(val.Index(i)))
+			if len(errArr) > 0 {
+				returnErr = append(returnErr, errArr...)
+				if params.lazyFlag {
+					return
+				}
+			}
+		}
+	case reflect.Map:
+		for _, key := range val.MapKeys() {
+			elemPath := indexPath(path, formatMapKey(key))
+			if len(keyRules) > 0 {
+				keyValue, keyNil := indirectValue(key)
+				errArr, _ := self.validateRules(keyRules, name, allKey+"_"+VALIDATOR_KEYS, elemPath, params, parent, keyValue, keyNil, !keyNil && isPresentPtr(key))
+				if len(errArr) > 0 {
+					returnErr = append(returnErr, errArr...)
+					if params.lazyFlag {
+						return
+					}
+				}
+			}
+			elem, elemNil := indirectValue(val.MapIndex(key))
+			errArr, _ := self.validateRules(rules, name, allKey, elemPath, params, parent, elem, elemNil, !elemNil && isPresentPtr(val.MapIndex(key)))
+			if len(errArr) > 0 {
+				returnErr = append(returnErr, errArr...)
+				if params.lazyFlag {
+					return
+				}
+			}
+		}
+	default:
+		err := formatError("[name] is not a array/map/slice, can not dive", map[string]string{"name": name})
+		returnErr = append(returnErr, toValidationError(err, name, path, VALIDATOR_DIVE, nil, val))
+	}
+	return
+}
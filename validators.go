@@ -1,12 +1,13 @@
 package govalidators
 
 import (
+	"context"
 	// "errors"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
-	"regexp"
 	"strconv"
-	"strings"
 	"sync"
 	"unicode/utf8"
 )
@@ -23,6 +24,37 @@ func (f ValidatorF) Validate(params map[string]interface{}, val reflect.Value, a
 	return f(params, val, args...)
 }
 
+//带 context 的验证接口，适用于需要访问缓存、数据库等的验证器，可以响应 context 的取消和超时，获取 context 中的租户、语言等信息
+type ValidatorCtx interface {
+	Validate(ctx context.Context, params map[string]interface{}, val reflect.Value, args ...string) (bool, error)
+}
+
+//带 context 的验证函数
+type ValidatorCtxF func(ctx context.Context, params map[string]interface{}, val reflect.Value, args ...string) (bool, error)
+
+func (f ValidatorCtxF) Validate(ctx context.Context, params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+	return f(ctx, params, val, args...)
+}
+
+//将 ValidatorCtx 转为 Validator，context 从 params["ctx"] 中获取，没有时使用 context.Background()
+type ctxValidator struct {
+	validator ValidatorCtx
+}
+
+func (self ctxValidator) Validate(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+	ctx, ok := params["ctx"].(context.Context)
+	if !ok || ctx == nil {
+		ctx = context.Background()
+	}
+	return self.validator.Validate(ctx, params, val, args...)
+}
+
+//自验证接口，实现该接口的类型(如 Money、DateRange)在验证时会自动调用 ValidateSelf，不需要在每个使用的地方配置 tag
+//方法名不使用 Validate，避免 func (r *Req) Validate() error { return v.Validate(r) } 这类封装被自动调用导致无限递归
+type Validatable interface {
+	ValidateSelf() error
+}
+
 type Range struct {
 	Min       string
 	Max       string
@@ -36,7 +68,7 @@ func (self *Range) InitRangeNum(eParamsMap map[string]string, args ...string) er
 	self.min, self.max = self.Min, self.Max
 	argsL := len(args)
 	if (self.Min == "" && argsL == 0) || argsL > 2 {
-		return formatError("[name] validator range error", eParamsMap)
+		return formatConfigError("[name] validator range error", eParamsMap)
 	}
 	if argsL == 1 {
 		self.min = args[0]
@@ -48,10 +80,15 @@ func (self *Range) InitRangeNum(eParamsMap map[string]string, args ...string) er
 }
 
 func (self *Range) CompareFloat(valNum float64, eParamsMap map[string]string, errorMap map[string]string) error {
+	return self.CompareFloatBits(valNum, 64, eParamsMap, errorMap)
+}
+
+//bitSize 为值的精度，float32 的值按 float32 解析范围，避免 float32(0.1) 和 0.1 比较时不相等
+func (self *Range) CompareFloatBits(valNum float64, bitSize int, eParamsMap map[string]string, errorMap map[string]string) error {
 	if self.min == "" ||
-		(self.min != VALIDATOR_IGNORE_SIGN && !regexp.MustCompile(FLOAT_REG).MatchString(self.min)) ||
-		(self.max != VALIDATOR_IGNORE_SIGN && !regexp.MustCompile(FLOAT_REG).MatchString(self.max) && self.max != "") {
-		return formatError("[name] validator range error", eParamsMap)
+		(self.min != VALIDATOR_IGNORE_SIGN && !floatRegexp.MatchString(self.min)) ||
+		(self.max != VALIDATOR_IGNORE_SIGN && !floatRegexp.MatchString(self.max) && self.max != "") {
+		return formatConfigError("[name] validator range error", eParamsMap)
 	}
 	if self.min == VALIDATOR_IGNORE_SIGN && (self.max == VALIDATOR_IGNORE_SIGN || self.max == "") {
 		return nil
@@ -60,26 +97,29 @@ func (self *Range) CompareFloat(valNum float64, eParamsMap map[string]string, er
 	var ok bool
 	var errKey, errStr string
 	if self.min == VALIDATOR_IGNORE_SIGN {
-		max, _ = strconv.ParseFloat(self.max, 64)
+		max, _ = strconv.ParseFloat(self.max, bitSize)
 		if valNum > max {
 			errKey = "lessThan"
 			eParamsMap["max"] = self.max
 		}
 	} else if self.max == VALIDATOR_IGNORE_SIGN {
-		min, _ = strconv.ParseFloat(self.min, 64)
+		min, _ = strconv.ParseFloat(self.min, bitSize)
 		if valNum < min {
 			errKey = "atLeast"
 			eParamsMap["min"] = self.min
 		}
 	} else if self.max == "" {
-		min, _ = strconv.ParseFloat(self.min, 64)
+		min, _ = strconv.ParseFloat(self.min, bitSize)
 		if valNum != min {
 			errKey = "equal"
 			eParamsMap["min"] = self.min
 		}
 	} else {
-		max, _ = strconv.ParseFloat(self.max, 64)
-		min, _ = strconv.ParseFloat(self.min, 64)
+		max, _ = strconv.ParseFloat(self.max, bitSize)
+		min, _ = strconv.ParseFloat(self.min, bitSize)
+		if min >= max {
+			return formatConfigError("[name] validator range error", eParamsMap)
+		}
 		if valNum < min || valNum > max {
 			errKey = "between"
 			eParamsMap["min"] = self.min
@@ -95,44 +135,60 @@ func (self *Range) CompareFloat(valNum float64, eParamsMap map[string]string, er
 	return formatError(errStr, eParamsMap)
 }
 
+//判断范围是否都是整数或 _
+func (self *Range) isIntegerRange() bool {
+	return (self.min == VALIDATOR_IGNORE_SIGN || integerRegexp.MatchString(self.min)) &&
+		(self.max == VALIDATOR_IGNORE_SIGN || self.max == "" || integerRegexp.MatchString(self.max))
+}
+
 func (self *Range) CompareInteger(valNum int64, eParamsMap map[string]string, errorMap map[string]string) error {
+	return self.compareBigInt(big.NewInt(valNum), eParamsMap, errorMap)
+}
+
+//无符号整数比较，支持 uint64 全范围的值和范围
+func (self *Range) CompareUint(valNum uint64, eParamsMap map[string]string, errorMap map[string]string) error {
+	return self.compareBigInt(new(big.Int).SetUint64(valNum), eParamsMap, errorMap)
+}
+
+//整数统一转为 big.Int 比较，范围可以超出 int64、uint64 的表示范围
+func (self *Range) compareBigInt(valNum *big.Int, eParamsMap map[string]string, errorMap map[string]string) error {
 	if self.min == "" ||
-		(self.min != VALIDATOR_IGNORE_SIGN && !regexp.MustCompile(INTEGER_REG).MatchString(self.min)) ||
-		(self.max != VALIDATOR_IGNORE_SIGN && !regexp.MustCompile(INTEGER_REG).MatchString(self.max) && self.max != "") {
-		return formatError("[name] validator range error", eParamsMap)
+		(self.min != VALIDATOR_IGNORE_SIGN && !integerRegexp.MatchString(self.min)) ||
+		(self.max != VALIDATOR_IGNORE_SIGN && !integerRegexp.MatchString(self.max) && self.max != "") {
+		return formatConfigError("[name] validator range error", eParamsMap)
 	}
 	if self.min == VALIDATOR_IGNORE_SIGN && (self.max == VALIDATOR_IGNORE_SIGN || self.max == "") {
 		return nil
 	}
 
-	var min, max int64
+	min, max := new(big.Int), new(big.Int)
 	var ok bool
 	var errKey, errStr string
 	if self.min == VALIDATOR_IGNORE_SIGN {
-		max, _ = strconv.ParseInt(self.max, 10, 64)
-		if valNum > max {
+		max.SetString(self.max, 10)
+		if valNum.Cmp(max) > 0 {
 			errKey = "lessThan"
 			eParamsMap["max"] = self.max
 		}
 	} else if self.max == VALIDATOR_IGNORE_SIGN {
-		min, _ = strconv.ParseInt(self.min, 10, 64)
-		if valNum < min {
+		min.SetString(self.min, 10)
+		if valNum.Cmp(min) < 0 {
 			errKey = "atLeast"
 			eParamsMap["min"] = self.min
 		}
 	} else if self.max == "" {
-		min, _ = strconv.ParseInt(self.min, 10, 64)
-		if valNum != min {
+		min.SetString(self.min, 10)
+		if valNum.Cmp(min) != 0 {
 			errKey = "equal"
 			eParamsMap["min"] = self.min
 		}
 	} else {
-		max, _ = strconv.ParseInt(self.max, 10, 64)
-		min, _ = strconv.ParseInt(self.min, 10, 64)
-		if min >= max {
-			return formatError("[name] validator range error", eParamsMap)
+		max.SetString(self.max, 10)
+		min.SetString(self.min, 10)
+		if min.Cmp(max) >= 0 {
+			return formatConfigError("[name] validator range error", eParamsMap)
 		}
-		if valNum < min || valNum > max {
+		if valNum.Cmp(min) < 0 || valNum.Cmp(max) > 0 {
 			errKey = "between"
 			eParamsMap["min"] = self.min
 			eParamsMap["max"] = self.max
@@ -160,12 +216,161 @@ func (self *RequiredValidator) Validate(params map[string]interface{}, val refle
 		eMsg = self.EMsg
 	}
 
-	if isZeroValue(val) {
+	if isAbsentValue(params, val) {
+		return false, formatError(eMsg, eParamsMap)
+	}
+	return true, nil
+}
+
+/**
+ * 条件必填，同级字段的值都等于对应的参数时，当前字段必填
+ * 栗子
+ * required_if=NeedInvoice,true 表示 NeedInvoice 为 true 时必填
+ * required_if=Type,1,Status,2 表示 Type 为 1 并且 Status 为 2 时必填
+ */
+type RequiredIfValidator struct {
+	EMsg string
+}
+
+func (self *RequiredIfValidator) Validate(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+	eMsg := "[name] is must required"
+	eParamsMap := map[string]string{
+		"name": params["name"].(string),
+	}
+	if self.EMsg != "" {
+		eMsg = self.EMsg
+	}
+	matched, err := matchFields(params, eParamsMap, args...)
+	if err != nil {
+		return false, err
+	}
+	if matched && isAbsentValue(params, val) {
+		return false, formatError(eMsg, eParamsMap)
+	}
+	return true, nil
+}
+
+/**
+ * 条件必填，除非同级字段的值都等于对应的参数，否则当前字段必填
+ * 栗子
+ * required_unless=Type,1 表示 Type 不为 1 时必填
+ */
+type RequiredUnlessValidator struct {
+	EMsg string
+}
+
+func (self *RequiredUnlessValidator) Validate(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+	eMsg := "[name] is must required"
+	eParamsMap := map[string]string{
+		"name": params["name"].(string),
+	}
+	if self.EMsg != "" {
+		eMsg = self.EMsg
+	}
+	matched, err := matchFields(params, eParamsMap, args...)
+	if err != nil {
+		return false, err
+	}
+	if !matched && isAbsentValue(params, val) {
+		return false, formatError(eMsg, eParamsMap)
+	}
+	return true, nil
+}
+
+/**
+ * 条件必填，任意一个同级字段不为零值时，当前字段必填
+ * 栗子
+ * required_with=Email,Phone 表示 Email 或 Phone 不为空时必填
+ */
+type RequiredWithValidator struct {
+	EMsg string
+}
+
+func (self *RequiredWithValidator) Validate(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+	eMsg := "[name] is must required"
+	eParamsMap := map[string]string{
+		"name": params["name"].(string),
+	}
+	if self.EMsg != "" {
+		eMsg = self.EMsg
+	}
+	anyPresent, _, err := presentFields(params, eParamsMap, args...)
+	if err != nil {
+		return false, err
+	}
+	if anyPresent && isAbsentValue(params, val) {
 		return false, formatError(eMsg, eParamsMap)
 	}
 	return true, nil
 }
 
+/**
+ * 条件必填，任意一个同级字段为零值时，当前字段必填
+ * 栗子
+ * required_without=Email 表示 Email 为空时必填
+ */
+type RequiredWithoutValidator struct {
+	EMsg string
+}
+
+func (self *RequiredWithoutValidator) Validate(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+	eMsg := "[name] is must required"
+	eParamsMap := map[string]string{
+		"name": params["name"].(string),
+	}
+	if self.EMsg != "" {
+		eMsg = self.EMsg
+	}
+	_, anyAbsent, err := presentFields(params, eParamsMap, args...)
+	if err != nil {
+		return false, err
+	}
+	if anyAbsent && isAbsentValue(params, val) {
+		return false, formatError(eMsg, eParamsMap)
+	}
+	return true, nil
+}
+
+//判断同级字段的值是否都等于对应的参数，args 为 字段,值,字段,值...
+func matchFields(params map[string]interface{}, eParamsMap map[string]string, args ...string) (bool, error) {
+	if len(args) == 0 || len(args)%2 != 0 {
+		return false, formatConfigError("[name] validator args error", eParamsMap)
+	}
+	parent, _ := params["parent"].(reflect.Value)
+	for i := 0; i < len(args); i += 2 {
+		field, ok := lookupField(parent, args[i])
+		if !ok {
+			eParamsMap["field"] = args[i]
+			return false, formatConfigError("[name] validator field [field] not exist", eParamsMap)
+		}
+		if !equalString(field, args[i+1]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+//判断同级字段中，是否有不为零值的字段、是否有为零值的字段
+func presentFields(params map[string]interface{}, eParamsMap map[string]string, args ...string) (anyPresent, anyAbsent bool, err error) {
+	if len(args) == 0 {
+		return false, false, formatConfigError("[name] validator args error", eParamsMap)
+	}
+	parent, _ := params["parent"].(reflect.Value)
+	for _, arg := range args {
+		field, ok := lookupField(parent, arg)
+		if !ok {
+			eParamsMap["field"] = arg
+			return false, false, formatConfigError("[name] validator field [field] not exist", eParamsMap)
+		}
+		if isZeroValue(field) {
+			anyAbsent = true
+		} else {
+			anyPresent = true
+		}
+	}
+	return
+}
+
 /**
  * 当只有 Min 或者 Max 的值，另一个值为 nil 时，验证器为等于有值的对应值
  * 当只有 Min 或者 Max 的值，另一个值为 _ 时，验证器为忽略带 _ 的值
@@ -214,6 +419,7 @@ func (self *StringValidator) Validate(params map[string]interface{}, val reflect
  * integer=1,2 表示 Min=1,Max=2,就是说 1 <= num <= 2
  * integer=1 表示 Min=1,Max=nil,就是说 num = 1
  * integer=1,_ 表示 Min=1,Max=_,就是说 1 <= num
+ * 值为整数的 float(如 json 解析得到的 5)同样视为 integer
  */
 type IntegerValidator struct {
 	EMsg string
@@ -228,7 +434,9 @@ func (self *IntegerValidator) Validate(params map[string]interface{}, val reflec
 	if self.EMsg != "" {
 		eMsg = self.EMsg
 	}
-	if !checkNumber(val.Kind(), INTEGER_KIND) {
+	//json 解析到 interface{} 中的数字都是 float64，值为整数时同样视为 integer
+	isFloat := checkNumber(val.Kind(), FLOAT_KIND)
+	if isFloat && !isWholeFloat(val.Float()) || !isFloat && !checkNumber(val.Kind(), INTEGER_KIND) {
 		return false, formatError(eMsg, eParamsMap)
 	}
 	//后边不接参数，表示只判断类型
@@ -239,7 +447,118 @@ func (self *IntegerValidator) Validate(params map[string]interface{}, val reflec
 	if err != nil {
 		return false, err
 	}
-	err = self.CompareInteger(val.Int(), eParamsMap, numberErrorMap)
+	if isFloat {
+		err = self.CompareFloatBits(val.Float(), val.Type().Bits(), eParamsMap, numberErrorMap)
+	} else if isUint(val.Kind()) {
+		err = self.CompareUint(val.Uint(), eParamsMap, numberErrorMap)
+	} else {
+		err = self.CompareInteger(val.Int(), eParamsMap, numberErrorMap)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+/**
+ * 当只有 Min 或者 Max 的值，另一个值为 nil 时，验证器为等于有值的对应值
+ * 当只有 Min 或者 Max 的值，另一个值为 _ 时，验证器为忽略带 _ 的值
+ * NaN 和 ±Inf 不是合法的值
+ * 栗子
+ * float=1.5,2 表示 Min=1.5,Max=2,就是说 1.5 <= num <= 2
+ * float=1.5 表示 Min=1.5,Max=nil,就是说 num = 1.5
+ * float=1.5,_ 表示 Min=1.5,Max=_,就是说 1.5 <= num
+ */
+type FloatValidator struct {
+	EMsg       string
+	FiniteEMsg string
+	Range
+}
+
+func (self *FloatValidator) Validate(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+	eMsg := "[name] is not a float"
+	finiteEMsg := "[name] is not a finite number"
+	eParamsMap := map[string]string{
+		"name": params["name"].(string),
+	}
+	if self.EMsg != "" {
+		eMsg = self.EMsg
+	}
+	if self.FiniteEMsg != "" {
+		finiteEMsg = self.FiniteEMsg
+	}
+	if !checkNumber(val.Kind(), FLOAT_KIND) {
+		return false, formatError(eMsg, eParamsMap)
+	}
+	valNum := val.Float()
+	if math.IsNaN(valNum) || math.IsInf(valNum, 0) {
+		return false, formatError(finiteEMsg, eParamsMap)
+	}
+	//后边不接参数，表示只判断类型
+	if len(args) == 0 {
+		return true, nil
+	}
+	err := self.InitRangeNum(eParamsMap, args...)
+	if err != nil {
+		return false, err
+	}
+	err = self.CompareFloatBits(valNum, val.Type().Bits(), eParamsMap, numberErrorMap)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+/**
+ * 数字验证器，支持整数和浮点数，范围规则同 integer、float
+ * 栗子
+ * number=0,99.5 表示 0 <= num <= 99.5
+ */
+type NumberValidator struct {
+	EMsg       string
+	FiniteEMsg string
+	Range
+}
+
+func (self *NumberValidator) Validate(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+	eMsg := "[name] is not a number"
+	finiteEMsg := "[name] is not a finite number"
+	eParamsMap := map[string]string{
+		"name": params["name"].(string),
+	}
+	if self.EMsg != "" {
+		eMsg = self.EMsg
+	}
+	if self.FiniteEMsg != "" {
+		finiteEMsg = self.FiniteEMsg
+	}
+	if !checkNumber(val.Kind()) {
+		return false, formatError(eMsg, eParamsMap)
+	}
+	isFloat := checkNumber(val.Kind(), FLOAT_KIND)
+	if isFloat && (math.IsNaN(val.Float()) || math.IsInf(val.Float(), 0)) {
+		return false, formatError(finiteEMsg, eParamsMap)
+	}
+	//后边不接参数，表示只判断类型
+	if len(args) == 0 {
+		return true, nil
+	}
+	err := self.InitRangeNum(eParamsMap, args...)
+	if err != nil {
+		return false, err
+	}
+	//整数并且范围也是整数时按整数比较，避免转为 float64 后丢失精度
+	if isFloat {
+		err = self.CompareFloatBits(val.Float(), val.Type().Bits(), eParamsMap, numberErrorMap)
+	} else if isUint(val.Kind()) && self.isIntegerRange() {
+		err = self.CompareUint(val.Uint(), eParamsMap, numberErrorMap)
+	} else if isUint(val.Kind()) {
+		err = self.CompareFloat(float64(val.Uint()), eParamsMap, numberErrorMap)
+	} else if self.isIntegerRange() {
+		err = self.CompareInteger(val.Int(), eParamsMap, numberErrorMap)
+	} else {
+		err = self.CompareFloat(float64(val.Int()), eParamsMap, numberErrorMap)
+	}
 	if err != nil {
 		return false, err
 	}
@@ -326,7 +645,7 @@ func (self *InValidator) Validate(params map[string]interface{}, val reflect.Val
 		valsI = append(valsI, val)
 	}
 	if !checkBool(kind) && !checkNumber(kind) && !checkString(kind) {
-		return false, formatError(typeEMsg, eParamsMap)
+		return false, formatConfigError(typeEMsg, eParamsMap)
 	}
 	if len(valsI) == 0 {
 		return false, formatError(eMsg, eParamsMap)
@@ -363,16 +682,27 @@ func (self *EmailValidator) Validate(params map[string]interface{}, val reflect.
 	if !checkString(val.Kind()) {
 		return false, formatError(eMsg, eParamsMap)
 	}
-	reg := MAIL_REG
+	reg := mailRegexp
 	if self.Reg != "" {
-		reg = self.Reg
+		var err error
+		if reg, err = compileRegexp(self.Reg); err != nil {
+			return false, formatConfigError("[name] validator reg error", eParamsMap)
+		}
 	}
-	if !regexp.MustCompile(reg).MatchString(val.String()) {
+	if !reg.MatchString(val.String()) {
 		return false, formatError(eMsg, eParamsMap)
 	}
 	return true, nil
 }
 
+func (self *EmailValidator) checkReg() error {
+	if self.Reg == "" {
+		return nil
+	}
+	_, err := compileRegexp(self.Reg)
+	return err
+}
+
 type UrlValidator struct {
 	EMsg string
 	Reg  string
@@ -389,16 +719,27 @@ func (self *UrlValidator) Validate(params map[string]interface{}, val reflect.Va
 	if !checkString(val.Kind()) {
 		return false, formatError(eMsg, eParamsMap)
 	}
-	reg := URL_REG
+	reg := urlRegexp
 	if self.Reg != "" {
-		reg = self.Reg
+		var err error
+		if reg, err = compileRegexp(self.Reg); err != nil {
+			return false, formatConfigError("[name] validator reg error", eParamsMap)
+		}
 	}
-	if !regexp.MustCompile(reg).MatchString(val.String()) {
+	if !reg.MatchString(val.String()) {
 		return false, formatError(eMsg, eParamsMap)
 	}
 	return true, nil
 }
 
+func (self *UrlValidator) checkReg() error {
+	if self.Reg == "" {
+		return nil
+	}
+	_, err := compileRegexp(self.Reg)
+	return err
+}
+
 type DateTimeValidator struct {
 	EMsg   string
 	FmtStr string
@@ -422,18 +763,24 @@ func (self *DateTimeValidator) Validate(params map[string]interface{}, val refle
 	if !checkString(val.Kind()) {
 		return false, formatError(eMsg, eParamsMap)
 	}
-	//拼接
-	replaceArr := []string{
-		"Y", YEAR_REG, "m", MONTH_REF, "d", DAY_REF, "H", HOUR_REF, "i", MINUTE_REF, "s", SECOND_REF,
+	reg, err := dateTimeRegexp(fmtStr)
+	if err != nil {
+		return false, formatConfigError("[name] validator datetime format error", eParamsMap)
 	}
-	replacer := strings.NewReplacer(replaceArr...)
-	reg := `^` + replacer.Replace(fmtStr) + `$`
-	if !regexp.MustCompile(reg).MatchString(val.String()) {
+	if !reg.MatchString(val.String()) {
 		return false, formatError(eMsg, eParamsMap)
 	}
 	return true, nil
 }
 
+func (self *DateTimeValidator) checkReg() error {
+	if self.FmtStr == "" {
+		return nil
+	}
+	_, err := dateTimeRegexp(self.FmtStr)
+	return err
+}
+
 /**
  * 仅支持 string、float、int、bool 类型
  * 或值类型为 string、float、int、bool 类型的array、slice、map
@@ -459,7 +806,7 @@ func (self *UniqueValidator) Validate(params map[string]interface{}, val reflect
 	case reflect.Slice, reflect.Array:
 		kind = val.Type().Elem().Kind()
 		if !checkBool(kind) && !checkNumber(kind) && !checkString(kind) {
-			return false, formatError(typeEMsg, eParamsMap)
+			return false, formatConfigError(typeEMsg, eParamsMap)
 		}
 		arrLen := val.Len()
 		for i := 0; i < arrLen; i++ {
@@ -474,7 +821,7 @@ func (self *UniqueValidator) Validate(params map[string]interface{}, val reflect
 		}
 	default:
 		if !checkBool(kind) && !checkNumber(kind) && !checkString(kind) {
-			return false, formatError(typeEMsg, eParamsMap)
+			return false, formatConfigError(typeEMsg, eParamsMap)
 		}
 		tmpK := fmt.Sprintf("%v_%v", allKey, val)
 		_, ok := syncMap.Load(tmpK)
@@ -486,3 +833,63 @@ func (self *UniqueValidator) Validate(params map[string]interface{}, val reflect
 	}
 	return true, nil
 }
+
+/**
+ * 字段比较验证器，比较当前字段和同级字段的值，参数为同级字段名，也可以是 . 分隔的嵌套字段路径，如 gtfield=Period.BeginTime
+ * 支持数字、字符串(包括相同格式的 datetime 字符串)、bool(仅 eqfield、nefield)、time.Time
+ * 栗子
+ * eqfield=Password 表示等于 Password 字段
+ * nefield=OldPassword 表示不等于 OldPassword 字段
+ * gtfield=BeginTime、gtefield=MinPrice、ltfield=EndTime、ltefield=MaxPrice 分别表示大于、大于等于、小于、小于等于
+ */
+type FieldCompareValidator struct {
+	EMsg     string
+	TypeEMsg string
+	Op       string //eq、ne、gt、gte、lt、lte
+}
+
+func (self *FieldCompareValidator) Validate(params map[string]interface{}, val reflect.Value, args ...string) (bool, error) {
+	eMsg := fieldCompareErrorMap[self.Op]
+	typeEMsg := "[name] can not compare with [field]"
+	eParamsMap := map[string]string{
+		"name": params["name"].(string),
+	}
+	if self.EMsg != "" {
+		eMsg = self.EMsg
+	}
+	if self.TypeEMsg != "" {
+		typeEMsg = self.TypeEMsg
+	}
+	if len(args) != 1 || eMsg == "" {
+		return false, formatConfigError("[name] validator field compare error", eParamsMap)
+	}
+	eParamsMap["field"] = args[0]
+	parent, _ := params["parent"].(reflect.Value)
+	other, ok := lookupField(parent, args[0])
+	if !ok {
+		return false, formatConfigError("[name] validator field [field] not exist", eParamsMap)
+	}
+	cmp, ok := compareValue(val, other)
+	if !ok || (checkBool(val.Kind()) && self.Op != "eq" && self.Op != "ne") {
+		return false, formatConfigError(typeEMsg, eParamsMap)
+	}
+	var valid bool
+	switch self.Op {
+	case "eq":
+		valid = cmp == 0
+	case "ne":
+		valid = cmp != 0
+	case "gt":
+		valid = cmp > 0
+	case "gte":
+		valid = cmp >= 0
+	case "lt":
+		valid = cmp < 0
+	case "lte":
+		valid = cmp <= 0
+	}
+	if !valid {
+		return false, formatError(eMsg, eParamsMap)
+	}
+	return true, nil
+}